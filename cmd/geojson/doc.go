@@ -5,12 +5,18 @@ USAGE:
    geojson [global options] command [command options] [arguments...]
 
 COMMANDS:
+   diff     compare two sub-area outputs by the IDs of their features
    serve    serve the web server
    subarea  list all sub-areas of an OpenStreetMap object
    help, h  Shows a list of commands or help for one command
 
 GLOBAL OPTIONS:
-   --out value, -o value  specify the directory of outputs (default: "./geo")
+   --cache value          cache full relations of the OpenStreetMap API in the directory by their versions
+   --cache-ttl value      serve cached relations without fetching them again for the duration (default: "24h")
+   --compress value       write compressed sidecars of outputs: gzip, br
+   --out value, -o value  specify the directory of outputs, "-" prints them instead (default: "./geo")
+   --template value       name outputs by {id}, {parent}, {name}, {variant}, {format} and {date}, e.g. "{parent}/{name}.{id}{variant}.{format}" (default: "{id}{variant}.{format}")
+   --upstream-rate value  limit requests to the OpenStreetMap API per second across all workers, 0 means unlimited (default: 0)
    --verbose              enable verbose logging with DEBUG level (default: false)
    --help, -h             show help (default: false)
    --version, -v          print the version (default: false)
@@ -21,12 +27,48 @@ SUBAREA
 geojson subarea - list all sub-areas of an OpenStreetMap object
 
 USAGE:
-   geojson subarea [command options] [arguments...]
+   geojson subarea [command options] <relation ID>... ("-" reads IDs from stdin)
 
 OPTIONS:
-   --raw, -r        leave tags in unfornalized form (UNF) (default: false)
-   --separated, -s  leave sub-areas unmerged (default: false)
-   --help, -h       show help (default: false)
+   --raw, -r               leave tags in unfornalized form (UNF) (default: false)
+   --separated, -s         leave sub-areas unmerged (default: false)
+   --rewind                rewind the output - counter to RFC 7946 (default: false)
+   --precision value       round coordinates to N decimal places, negative values keep them intact (default: -1)
+   --validate value        validate geometries: warn, fix or fail
+   --centroid              add the centroid of each sub-area to its properties (default: false)
+   --label-point           add a point guaranteed to lie inside each sub-area to its properties (default: false)
+   --measure value         add geodesic area and perimeter of each sub-area to its properties: m or km
+   --id value              format feature IDs: osm ("relation/962876"), numeric (962876), prefixed (3962876) or property:<name> (default: "osm")
+   --sort value            order merged sub-areas by a property or a tag, then by their IDs (default: IDs only)
+   --strict                exit with 2 if any sub-area fails, same as --allow-failures 0 (default: false)
+   --allow-failures value  exit with 2 if more than N sub-areas fail, the merged output is skipped as well. Negative values mean unlimited (default: -1)
+   --report value          write a JSON report of succeeded, failed and skipped members to the path
+   --meta                  add version, timestamp, changeset and user of each sub-area and the parent (default: false)
+   --checkpoint value      record finished parents and sub-areas along with their versions to the file
+   --resume                skip parents and sub-areas finished according to --checkpoint, failed ones are retried (default: false)
+   --update                regenerate sub-areas only if they, their member ways or their nodes have changed since the previous outputs, unchanged files are left intact (default: false)
+   --at value              build sub-areas as they were at the date, e.g. "2019-01-01", or the RFC 3339 timestamp
+   --version value         build sub-areas as they were at the version of the relation (default: 0)
+   --history value         build snapshots from a full-history file instead of the history API: .osh.pbf, .osh or .osh.gz
+   --ids-file value        read relation IDs separated by whitespaces or commas from the file, "#" starts a comment
+   --tags value            keep tags matching the patterns, e.g. "name:*" (default: name, type)
+   --all-tags              keep all tags (default: false)
+   --normalize value       normalize tags matching the patterns only (default: all kept tags)
+   --normalizer value      normalize tags by: strip (diacritics), ascii (transliteration) or slug (default: "strip")
+   --mapping value         reshape tags into properties by a mapping file (YAML or JSON)
+   --lang value            resolve names in the languages by the order of preference, e.g. "vi,en"
+   --help, -h              show help (default: false)
+
+DIFF
+
+geojson diff - compare two sub-area outputs by the IDs of their features
+
+USAGE:
+   geojson diff [command options] <old> <new>
+
+OPTIONS:
+   --format value  report as: text, json or geojson of the changed features (default: "text")
+   --help, -h      show help (default: false)
 
 SERVE
 
@@ -42,6 +84,15 @@ OPTIONS:
    --rate-burst value             set burst size (concurrent requests) for rate-limiting (default: 5)
    --rate-ttl value               set the rate limit TTL for inactive sessions (default: "2m")
    --prefix value                 set static fs handler base path (default: "/static")
+   --replication value            mark outputs touched by osmChange diffs of the replication URL or directory as stale, e.g. "https://planet.osm.org/replication/minute"
+   --replication-interval value   set the polling interval of replication diffs (default: "1m")
+   --id value                     format feature IDs: osm ("relation/962876"), numeric (962876), prefixed (3962876) or property:<name> (default: "osm")
+   --tags value                   keep tags matching the patterns, e.g. "name:*" (default: name, type)
+   --all-tags                     keep all tags (default: false)
+   --normalize value              normalize tags matching the patterns only (default: all kept tags)
+   --normalizer value             normalize tags by: strip (diacritics), ascii (transliteration) or slug (default: "strip")
+   --mapping value                reshape tags into properties by a mapping file (YAML or JSON)
+   --lang value                   resolve names in the languages by the order of preference, e.g. "vi,en"
    --help, -h                     show help (default: false)
 */
 package main
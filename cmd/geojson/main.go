@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"log"
 	"os"
@@ -14,12 +15,18 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+// Exit codes
+const (
+	constExitError    = 1
+	constExitFailures = 2
+)
+
 // NewSubAreaCommand constructs sub-command SubArea.
 func NewSubAreaCommand() func(c *cli.Context) error {
 	return func(c *cli.Context) error {
-		relation := c.Args().First()
-		if relation == "" {
-			return errors.New("invalid OpenStreetMap relation ID")
+		ids, err := relationIDs(c)
+		if err != nil {
+			return err
 		}
 
 		logger, ok := c.App.Metadata["logger"].(shared.Logger)
@@ -39,12 +46,183 @@ func NewSubAreaCommand() func(c *cli.Context) error {
 			return err
 		}
 
-		err = osm.SubAreas(ctx, relation)
+		validation, err := osm.ParseValidationMode(c.String("validate"))
 		if err != nil {
-			logger.Error(err)
+			return err
 		}
-		return nil
+
+		measure, err := osm.ParseMeasureUnit(c.String("measure"))
+		if err != nil {
+			return err
+		}
+
+		idFormat, err := osm.ParseIDFormat(c.String("id"))
+		if err != nil {
+			return err
+		}
+
+		ctx = osm.CtxSetPrecision(ctx, c.Int("precision"))
+		ctx = osm.CtxSetValidation(ctx, validation)
+		ctx = osm.CtxSetCentroid(ctx, c.Bool("centroid"))
+		ctx = osm.CtxSetLabelPoint(ctx, c.Bool("label-point"))
+		ctx = osm.CtxSetMeasure(ctx, measure)
+		ctx = osm.CtxSetMeta(ctx, c.Bool("meta"))
+		ctx = osm.CtxSetIDFormat(ctx, idFormat)
+		ctx = osm.CtxSetSort(ctx, c.String("sort"))
+		ctx, err = CtxSetTagOptions(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		ctx, err = CtxSetOutputOptions(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		ctx = osm.CtxSetAllowedFailures(ctx, allowedFailures(c))
+		ctx = osm.CtxSetReport(ctx, c.String("report"))
+		ctx = osm.CtxSetUpdate(ctx, c.Bool("update"))
+		ctx, err = CtxSetSnapshotOptions(ctx, c, len(ids))
+		if err != nil {
+			return err
+		}
+		ctx, err = CtxSetUpstreamOptions(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		checkpoint, err := openCheckpoint(c)
+		if err != nil {
+			return err
+		}
+
+		// nolint:errcheck
+		defer checkpoint.Close()
+
+		ctx = osm.CtxSetCheckpoint(ctx, checkpoint)
+		err = osm.SubAreasBatch(ctx, ids)
+		if errors.Is(err, osm.ErrFailures) {
+			return cli.Exit(err, constExitFailures)
+		}
+
+		return err
+	}
+}
+
+// relationIDs collects relation IDs from the arguments and the file of IDs. Argument "-" reads IDs from stdin.
+func relationIDs(c *cli.Context) ([]int64, error) {
+	ids := []int64{}
+	for _, arg := range c.Args().Slice() {
+		if arg == "-" {
+			stdin, err := util.ReadIDs(os.Stdin)
+			if err != nil {
+				return nil, err
+			}
+
+			ids = append(ids, stdin...)
+			continue
+		}
+
+		id, err := util.Int64FromString(arg)
+		if err != nil {
+			return nil, errors.New("invalid OpenStreetMap relation ID")
+		}
+
+		ids = append(ids, id)
+	}
+
+	file := c.String("ids-file")
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		read, err := util.ReadIDs(f)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, read...)
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("invalid OpenStreetMap relation ID")
+	}
+
+	// the same parent listed twice would be fetched and written twice, racing on its outputs
+	return util.UniqueInt64(ids), nil
+}
+
+// CtxSetSnapshotOptions sets options of historical snapshots from flags to an OpenStreetMap context.
+func CtxSetSnapshotOptions(ctx context.Context, c *cli.Context, parents int) (context.Context, error) {
+	at := time.Time{}
+	if c.String("at") != "" {
+		date, err := util.ParseDate(c.String("at"))
+		if err != nil {
+			return ctx, errors.New("invalid date")
+		}
+
+		at = date
+	}
+
+	version := c.Int("version")
+	if version != 0 && parents != 1 {
+		return ctx, errors.New("--version requires a single relation ID")
+	}
+
+	if version != 0 && !at.IsZero() {
+		return ctx, errors.New("--at and --version are exclusive")
+	}
+
+	if (version != 0 || !at.IsZero()) && c.Bool("update") {
+		return ctx, errors.New("--update can't be used with snapshots")
+	}
+
+	ctx = osm.CtxSetSnapshot(ctx, at, version)
+	file := c.String("history")
+	if file == "" {
+		return ctx, nil
+	}
+
+	if version == 0 && at.IsZero() {
+		return ctx, errors.New("--history requires --at or --version")
 	}
+
+	history, err := osm.ReadHistory(ctx, file)
+	if err != nil {
+		return ctx, err
+	}
+
+	return osm.CtxSetHistory(ctx, history), nil
+}
+
+// openCheckpoint opens the checkpoint if any. Nil means no checkpointing.
+func openCheckpoint(c *cli.Context) (*osm.Checkpoint, error) {
+	path := c.String("checkpoint")
+	if path == "" {
+		if c.Bool("resume") {
+			return nil, errors.New("--resume requires --checkpoint")
+		}
+
+		return nil, nil
+	}
+
+	if c.String("out") == "-" {
+		return nil, errors.New("--checkpoint requires an output directory")
+	}
+
+	return osm.OpenCheckpoint(path, c.Bool("resume"))
+}
+
+// allowedFailures interprets the failure policy. Negative values mean unlimited.
+func allowedFailures(c *cli.Context) int {
+	if c.Bool("strict") {
+		return 0
+	}
+
+	return c.Int("allow-failures")
 }
 
 // NewServeCommand constructs sub-command Serve.
@@ -75,6 +253,34 @@ func NewServeCommand() func(c *cli.Context) error {
 			return err
 		}
 
+		idFormat, err := osm.ParseIDFormat(c.String("id"))
+		if err != nil {
+			return err
+		}
+
+		ctx = osm.CtxSetIDFormat(ctx, idFormat)
+		ctx, err = CtxSetTagOptions(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		ctx, err = CtxSetOutputOptions(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		ctx, err = CtxSetUpstreamOptions(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		interval, err := util.ParseDuration(c.String("replication-interval"))
+		if err != nil {
+			return errors.New("invalid duration")
+		}
+
+		ctx = hxxp.CtxSetReplication(ctx, c.String("replication"), interval)
+
 		handler, err := hxxp.New(ctx)
 		if err != nil {
 			return errors.New("could not create the request handler")
@@ -84,6 +290,151 @@ func NewServeCommand() func(c *cli.Context) error {
 	}
 }
 
+// NewDiffCommand constructs sub-command Diff.
+func NewDiffCommand() func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return errors.New("invalid outputs, two paths are required")
+		}
+
+		format, err := osm.ParseDiffFormat(c.String("format"))
+		if err != nil {
+			return err
+		}
+
+		previous, err := osm.ReadFeatureCollection(c.Args().Get(0))
+		if err != nil {
+			return err
+		}
+
+		current, err := osm.ReadFeatureCollection(c.Args().Get(1))
+		if err != nil {
+			return err
+		}
+
+		report, err := osm.Diff(previous, current)
+		if err != nil {
+			return err
+		}
+
+		data, err := report.Marshal(format)
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+}
+
+// NewTagFlags constructs flags of tag processing shared by sub-commands.
+func NewTagFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "tags",
+			Usage: "keep tags matching the patterns, e.g. \"name:*\" (default: name, type)",
+		},
+		&cli.BoolFlag{
+			Name:  "all-tags",
+			Usage: "keep all tags",
+		},
+		&cli.StringSliceFlag{
+			Name:  "normalize",
+			Usage: "normalize tags matching the patterns only (default: all kept tags)",
+		},
+		&cli.StringFlag{
+			Name:  "normalizer",
+			Value: util.NormalizerStrip,
+			Usage: "normalize tags by: strip (diacritics), ascii (transliteration) or slug",
+		},
+		&cli.StringFlag{
+			Name:  "mapping",
+			Usage: "reshape tags into properties by a mapping file (YAML or JSON)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "lang",
+			Usage: "resolve names in the languages by the order of preference, e.g. \"vi,en\"",
+		},
+	}
+}
+
+// CtxSetOutputOptions sets output options from global flags to an OpenStreetMap context.
+func CtxSetOutputOptions(ctx context.Context, c *cli.Context) (context.Context, error) {
+	template, err := osm.ParseTemplate(c.String("template"))
+	if err != nil {
+		return ctx, err
+	}
+
+	encodings, err := util.ParseEncodings(c.StringSlice("compress"))
+	if err != nil {
+		return ctx, err
+	}
+
+	ctx = osm.CtxSetTemplate(ctx, template)
+	ctx = osm.CtxSetCompression(ctx, encodings)
+	return ctx, nil
+}
+
+// CtxSetUpstreamOptions sets options of the OpenStreetMap API from global flags to an OpenStreetMap context.
+func CtxSetUpstreamOptions(ctx context.Context, c *cli.Context) (context.Context, error) {
+	ctx = osm.CtxSetUpstreamRate(ctx, c.Float64("upstream-rate"))
+
+	dir := c.String("cache")
+	if dir == "" {
+		return ctx, nil
+	}
+
+	ttl, err := util.ParseDuration(c.String("cache-ttl"))
+	if err != nil {
+		return ctx, errors.New("invalid duration")
+	}
+
+	cache, err := osm.NewResponseCache(dir, ttl)
+	if err != nil {
+		return ctx, err
+	}
+
+	return osm.CtxSetResponseCache(ctx, cache), nil
+}
+
+// CtxSetTagOptions sets tag processing options from flags to an OpenStreetMap context.
+func CtxSetTagOptions(ctx context.Context, c *cli.Context) (context.Context, error) {
+	tags, err := osm.NewTagFilter(c.StringSlice("tags"), c.Bool("all-tags"))
+	if err != nil {
+		return ctx, err
+	}
+
+	normalized, err := osm.NewTagFilter(c.StringSlice("normalize"), false)
+	if err != nil {
+		return ctx, err
+	}
+
+	langs, err := util.ParseLanguages(c.StringSlice("lang"))
+	if err != nil {
+		return ctx, err
+	}
+
+	ctx = osm.CtxSetTags(ctx, tags)
+	ctx = osm.CtxSetNormalizedTags(ctx, normalized)
+	ctx = osm.CtxSetLanguages(ctx, langs)
+	ctx, err = osm.CtxSetNormalizer(ctx, c.String("normalizer"))
+	if err != nil {
+		return ctx, err
+	}
+
+	file := c.String("mapping")
+	if file == "" {
+		return ctx, nil
+	}
+
+	mapping, err := osm.ReadMapping(file)
+	if err != nil {
+		return ctx, err
+	}
+
+	return osm.CtxSetMapping(ctx, mapping), nil
+}
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "GeoJSON"
@@ -99,10 +450,11 @@ func main() {
 	app.Compiled = time.Now()
 	app.Commands = []*cli.Command{
 		{
-			Name:   "subarea",
-			Usage:  "list all sub-areas of an OpenStreetMap object",
-			Action: NewSubAreaCommand(),
-			Flags: []cli.Flag{
+			Name:      "subarea",
+			Usage:     "list all sub-areas of an OpenStreetMap object",
+			ArgsUsage: "<relation ID>... (\"-\" reads IDs from stdin)",
+			Action:    NewSubAreaCommand(),
+			Flags: append([]cli.Flag{
 				&cli.BoolFlag{
 					Name:    "raw",
 					Aliases: []string{"r"},
@@ -117,13 +469,101 @@ func main() {
 					Name:  "rewind",
 					Usage: "rewind the output - counter to RFC 7946",
 				},
+				&cli.IntFlag{
+					Name:  "precision",
+					Value: -1,
+					Usage: "round coordinates to N decimal places, negative values keep them intact",
+				},
+				&cli.StringFlag{
+					Name:  "validate",
+					Usage: "validate geometries: warn, fix or fail",
+				},
+				&cli.BoolFlag{
+					Name:  "centroid",
+					Usage: "add the centroid of each sub-area to its properties",
+				},
+				&cli.BoolFlag{
+					Name:  "label-point",
+					Usage: "add a point guaranteed to lie inside each sub-area to its properties",
+				},
+				&cli.StringFlag{
+					Name:  "measure",
+					Usage: "add geodesic area and perimeter of each sub-area to its properties: m or km",
+				},
+				&cli.StringFlag{
+					Name:  "id",
+					Value: "osm",
+					Usage: "format feature IDs: osm (\"relation/962876\"), numeric (962876), prefixed (3962876) or property:<name>",
+				},
+				&cli.StringFlag{
+					Name:  "sort",
+					Usage: "order merged sub-areas by a property or a tag, then by their IDs (default: IDs only)",
+				},
+				&cli.BoolFlag{
+					Name:  "strict",
+					Usage: "exit with 2 if any sub-area fails, same as --allow-failures 0",
+				},
+				&cli.IntFlag{
+					Name:  "allow-failures",
+					Value: -1,
+					Usage: "exit with 2 if more than N sub-areas fail, the merged output is skipped as well. Negative values mean unlimited",
+				},
+				&cli.StringFlag{
+					Name:  "report",
+					Usage: "write a JSON report of succeeded, failed and skipped members to the path",
+				},
+				&cli.BoolFlag{
+					Name:  "meta",
+					Usage: "add version, timestamp, changeset and user of each sub-area and the parent",
+				},
+				&cli.StringFlag{
+					Name:  "checkpoint",
+					Usage: "record finished parents and sub-areas along with their versions to the file",
+				},
+				&cli.BoolFlag{
+					Name:  "resume",
+					Usage: "skip parents and sub-areas finished according to --checkpoint, failed ones are retried",
+				},
+				&cli.BoolFlag{
+					Name:  "update",
+					Usage: "regenerate sub-areas only if they, their member ways or their nodes have changed since the previous outputs, unchanged files are left intact",
+				},
+				&cli.StringFlag{
+					Name:  "at",
+					Usage: "build sub-areas as they were at the date, e.g. \"2019-01-01\", or the RFC 3339 timestamp",
+				},
+				&cli.IntFlag{
+					Name:  "version",
+					Usage: "build sub-areas as they were at the version of the relation",
+				},
+				&cli.StringFlag{
+					Name:  "history",
+					Usage: "build snapshots from a full-history file instead of the history API: .osh.pbf, .osh or .osh.gz",
+				},
+				&cli.StringFlag{
+					Name:  "ids-file",
+					Usage: "read relation IDs separated by whitespaces or commas from the file, \"#\" starts a comment",
+				},
+			}, NewTagFlags()...),
+		},
+		{
+			Name:      "diff",
+			Usage:     "compare two sub-area outputs by the IDs of their features",
+			ArgsUsage: "<old> <new>",
+			Action:    NewDiffCommand(),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Value: "text",
+					Usage: "report as: text, json or geojson of the changed features",
+				},
 			},
 		},
 		{
 			Name:   "serve",
 			Usage:  "serve the web server",
 			Action: NewServeCommand(),
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				&cli.StringFlag{
 					Name:    "address",
 					Aliases: []string{"addr"},
@@ -155,7 +595,21 @@ func main() {
 					Value: "/static",
 					Usage: "set static fs handler base path",
 				},
-			},
+				&cli.StringFlag{
+					Name:  "replication",
+					Usage: "mark outputs touched by osmChange diffs of the replication URL or directory as stale, e.g. \"https://planet.osm.org/replication/minute\"",
+				},
+				&cli.StringFlag{
+					Name:  "replication-interval",
+					Value: "1m",
+					Usage: "set the polling interval of replication diffs",
+				},
+				&cli.StringFlag{
+					Name:  "id",
+					Value: "osm",
+					Usage: "format feature IDs: osm (\"relation/962876\"), numeric (962876), prefixed (3962876) or property:<name>",
+				},
+			}, NewTagFlags()...),
 		},
 	}
 	app.Flags = []cli.Flag{
@@ -166,9 +620,31 @@ func main() {
 		&cli.StringFlag{
 			Name:    "out",
 			Aliases: []string{"o"},
-			Usage:   "specify the directory of outputs",
+			Usage:   "specify the directory of outputs, \"-\" prints them instead",
 			Value:   "./geo",
 		},
+		&cli.StringFlag{
+			Name:  "cache",
+			Usage: "cache full relations of the OpenStreetMap API in the directory by their versions",
+		},
+		&cli.StringFlag{
+			Name:  "cache-ttl",
+			Value: "24h",
+			Usage: "serve cached relations without fetching them again for the duration",
+		},
+		&cli.StringSliceFlag{
+			Name:  "compress",
+			Usage: "write compressed sidecars of outputs: gzip, br",
+		},
+		&cli.Float64Flag{
+			Name:  "upstream-rate",
+			Usage: "limit requests to the OpenStreetMap API per second across all workers, 0 means unlimited",
+		},
+		&cli.StringFlag{
+			Name:  "template",
+			Value: "{id}{variant}.{format}",
+			Usage: "name outputs by {id}, {parent}, {name}, {variant}, {format} and {date}, e.g. \"{parent}/{name}.{id}{variant}.{format}\"",
+		},
 	}
 	app.Before = func(c *cli.Context) error {
 		logger, err := shared.NewLoggerZap(c.Bool("verbose"))
@@ -200,5 +676,5 @@ func main() {
 	}
 
 	log.Println(err)
-	os.Exit(1)
+	os.Exit(constExitError)
 }
@@ -3,6 +3,7 @@ module github.com/hiendv/geojson
 go 1.13
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/cpuguy83/go-md2man/v2 v2.0.0 // indirect
 	github.com/hashicorp/golang-lru v0.5.4
 	github.com/julienschmidt/httprouter v1.3.0
@@ -13,4 +14,5 @@ require (
 	go.uber.org/zap v1.15.0
 	golang.org/x/text v0.3.3
 	golang.org/x/time v0.0.0-20190921001708-c4c64cad1fd0
+	gopkg.in/yaml.v2 v2.4.0
 )
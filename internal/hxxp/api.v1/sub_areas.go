@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/golang-lru"
 	"github.com/hiendv/geojson/internal/osm"
 	"github.com/hiendv/geojson/internal/shared"
+	"github.com/hiendv/geojson/pkg/util"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -39,6 +41,7 @@ type Cache interface {
 	Add(key, value interface{})
 	Get(key interface{}) (value interface{}, ok bool)
 	Remove(key interface{})
+	Keys() []interface{}
 }
 
 // SubAreas constructs the routing group itself.
@@ -60,17 +63,46 @@ func SubAreas(logger shared.Logger, osmContext context.Context, handler Handler)
 	return &subAreasGroup{handler: handler, logger: logger, osmContext: osmContext, cache: cache, processing: map[int64]bool{}, errors: errorCache}, nil
 }
 
+// Query responds the static path of the requested output.
 func (group *subAreasGroup) Query(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	path, ok := group.resolve(w, r, params)
+	if !ok {
+		return
+	}
+
+	group.handler.Respond(w, "", group.handler.Static(path))
+}
+
+// BBox responds bounding boxes of the requested output without its geometry.
+func (group *subAreasGroup) BBox(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	path, ok := group.resolve(w, r, params)
+	if !ok {
+		return
+	}
+
+	extent, err := osm.ReadExtent(group.osmContext, path)
+	if err != nil {
+		group.logger.Error(err)
+		group.handler.Abort(w, "invalid outputs", http.StatusInternalServerError)
+		return
+	}
+
+	group.handler.Respond(w, "", extent)
+}
+
+// resolve looks for the output of the requested sub-areas and enqueues the processing if it doesn't exist.
+// The response is written unless the output is ready.
+func (group *subAreasGroup) resolve(w http.ResponseWriter, r *http.Request, params httprouter.Params) (string, bool) {
 	osmContext, err := osm.CtxBareClone(group.osmContext)
 	if err != nil {
 		group.handler.Abort(w, err.Error(), http.StatusInternalServerError)
-		return
+		return "", false
 	}
 
 	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
 	if err != nil {
 		group.handler.Error(w, errors.New("invalid ID"), http.StatusUnprocessableEntity)
-		return
+		return "", false
 	}
 
 	_, rewind := r.URL.Query()["rewind"]
@@ -78,24 +110,34 @@ func (group *subAreasGroup) Query(w http.ResponseWriter, r *http.Request, params
 		osmContext = osm.CtxSetRewind(osmContext, true)
 	}
 
-	cacheKey := fmt.Sprintf("%d-%v", id, rewind)
+	langs, err := requestLanguages(r)
+	if err != nil {
+		group.handler.Error(w, err, http.StatusUnprocessableEntity)
+		return "", false
+	}
+
+	if len(langs) != 0 {
+		osmContext = osm.CtxSetLanguages(osmContext, langs)
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+	cacheKey := fmt.Sprintf("%d-%v-%s", id, rewind, strings.Join(langs, "_"))
 	v, ok := group.cache.Get(cacheKey)
 	if ok {
 		path, ok := v.(string)
 		if !ok {
 			group.handler.Abort(w, "invalid path", http.StatusInternalServerError)
-			return
+			return "", false
 		}
 
 		err := osm.VerifyOutput(osmContext, path)
 		if err != nil {
 			group.cache.Remove(cacheKey)
 			group.handler.Abort(w, "missing outputs. try again.", http.StatusInternalServerError)
-			return
+			return "", false
 		}
 
-		group.handler.Respond(w, "", group.handler.Static(path))
-		return
+		return path, true
 	}
 
 	v, ok = group.errors.Get(id)
@@ -104,18 +146,18 @@ func (group *subAreasGroup) Query(w http.ResponseWriter, r *http.Request, params
 		if !ok {
 			group.errors.Remove(id)
 			group.handler.Abort(w, "invalid OSM error", http.StatusInternalServerError)
-			return
+			return "", false
 		}
 
 		if time.Since(osmErr.expiredAt) < 0 {
 			if osm.ErrIsClient(osmErr.err) {
 				group.handler.Abort(w, osmErr.err.Error(), http.StatusUnprocessableEntity)
-				return
+				return "", false
 			}
 
 			w.Header().Set("Retry-After", osmErr.expiredAt.UTC().Format(http.TimeFormat))
 			group.handler.Abort(w, osmErr.err.Error(), http.StatusServiceUnavailable)
-			return
+			return "", false
 		}
 
 		if time.Since(osmErr.expiredAt) >= 0 {
@@ -129,14 +171,13 @@ func (group *subAreasGroup) Query(w http.ResponseWriter, r *http.Request, params
 
 	if working {
 		group.handler.Respond(w, "check back later", nil)
-		return
+		return "", false
 	}
 
 	path, err := osm.FindSubAreas(osmContext, id)
 	if err == nil {
 		group.cache.Add(cacheKey, path)
-		group.handler.Respond(w, "", group.handler.Static(path))
-		return
+		return path, true
 	}
 
 	group.mu.Lock()
@@ -163,4 +204,31 @@ func (group *subAreasGroup) Query(w http.ResponseWriter, r *http.Request, params
 	}(group, id)
 
 	group.handler.Respond(w, "enqueued. check back later", nil)
+	return "", false
+}
+
+// Invalidate drops cached paths of the outputs, so they are looked up or regenerated by the next requests.
+func (group *subAreasGroup) Invalidate(paths []string) {
+	stale := map[string]bool{}
+	for _, path := range paths {
+		stale[path] = true
+	}
+
+	for _, key := range group.cache.Keys() {
+		v, ok := group.cache.Get(key)
+		path, isPath := v.(string)
+		if ok && isPath && stale[path] {
+			group.cache.Remove(key)
+		}
+	}
+}
+
+// requestLanguages looks for languages of a request in the "lang" query parameter then the Accept-Language header.
+func requestLanguages(r *http.Request) ([]string, error) {
+	lang := r.URL.Query().Get("lang")
+	if lang != "" {
+		return util.ParseLanguages(strings.Split(lang, ","))
+	}
+
+	return util.ParseAcceptLanguage(r.Header.Get("Accept-Language")), nil
 }
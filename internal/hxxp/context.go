@@ -18,6 +18,8 @@ const (
 	ctxKeyRateTTL   ctxKey = "rate-ttl"
 	ctxKeyOut       ctxKey = "out"
 	ctxKeyPrefix    ctxKey = "prefix"
+	ctxKeyReplica   ctxKey = "replication"
+	ctxKeyInterval  ctxKey = "replication-interval"
 )
 
 // NewContext is the utility to encapsulate pkg-scoped context values by preventing context key collision.
@@ -87,3 +89,20 @@ func ctxPrefix(ctx context.Context) (string, bool) {
 	v, ok := ctx.Value(ctxKeyPrefix).(string)
 	return v, ok
 }
+
+func ctxReplication(ctx context.Context) (string, time.Duration, bool) {
+	source, ok := ctx.Value(ctxKeyReplica).(string)
+	if !ok || source == "" {
+		return "", 0, false
+	}
+
+	interval, ok := ctx.Value(ctxKeyInterval).(time.Duration)
+	return source, interval, ok && interval > 0
+}
+
+// CtxSetReplication sets "replication" values to this context.
+// Diffs of the replication source are polled by the interval to mark stale outputs.
+func CtxSetReplication(ctx context.Context, source string, interval time.Duration) context.Context {
+	ctx = context.WithValue(ctx, ctxKeyReplica, source)
+	return context.WithValue(ctx, ctxKeyInterval, interval)
+}
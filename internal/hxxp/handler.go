@@ -74,11 +74,45 @@ func New(ctx context.Context) (handler *Handler, err error) {
 	router.GET("/", func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 		util.HTTPRespond(w, []byte(`Hello`))
 	})
-	router.ServeFiles(fmt.Sprintf("%s/%s/*filepath", prefix, filepath.Base(dir)), http.Dir(dir))
+	router.GET(fmt.Sprintf("%s/%s/*filepath", prefix, filepath.Base(dir)), serveStatic(dir))
 	router.GET("/api/v1/subareas/:id", v1SubAreas.Query)
+	router.GET("/api/v1/subareas/:id/bbox", v1SubAreas.BBox)
+
+	source, interval, ok := ctxReplication(ctx)
+	if ok {
+		replication, err := osm.NewReplication(source)
+		if err != nil {
+			return nil, err
+		}
+
+		go replicate(osmContext, replication, interval, v1SubAreas.Invalidate)
+	}
+
 	return
 }
 
+// replicate applies replication diffs by the interval until the context is done.
+func replicate(ctx context.Context, replication *osm.Replication, interval time.Duration, invalidate func(paths []string)) {
+	log := ctxLog(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		paths, err := replication.Apply(ctx)
+		if err != nil {
+			log.Warnw("replication failed", "error", err)
+		}
+
+		invalidate(paths)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // ServeHTTP serves HTTP requests.
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// exclude the static serving from middleware
@@ -117,7 +151,9 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	middleware.IP(
 		middleware.RateLimit(
 			middleware.CORS(
-				h.router,
+				middleware.Compress(
+					h.router,
+				),
 			),
 			rate,
 			rateBurst,
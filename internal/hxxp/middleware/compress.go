@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/hiendv/geojson/pkg/util"
+)
+
+// constCompressMin is the size of the smallest response worth compressing, smaller ones would barely shrink past the overhead of the encoding.
+const constCompressMin = 1024
+
+// compressedWriter buffers the beginning of a response until it's known to be worth compressing.
+type compressedWriter struct {
+	http.ResponseWriter
+	encoding    string
+	status      int
+	buffer      []byte
+	encoder     io.WriteCloser
+	passthrough bool // the response is written as is
+}
+
+// bodiless determines if responses of the status carry no body.
+func bodiless(status int) bool {
+	return status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified
+}
+
+func (w *compressedWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+
+	w.status = status
+	// the encoding would write its trailer as the body
+	if bodiless(status) || w.Header().Get("Content-Encoding") != "" {
+		w.pass()
+	}
+}
+
+func (w *compressedWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+
+	w.buffer = append(w.buffer, data...)
+	if len(w.buffer) < constCompressMin {
+		return len(data), nil
+	}
+
+	encoder, err := util.NewEncoder(w.ResponseWriter, w.encoding, util.CompressionFast)
+	if err != nil {
+		w.pass()
+		return len(data), w.flush()
+	}
+
+	w.Header().Set("Content-Encoding", w.encoding)
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.encoder = encoder
+	return len(data), w.flush()
+}
+
+// pass writes the response as is from now on.
+func (w *compressedWriter) pass() {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *compressedWriter) flush() error {
+	var writer io.Writer = w.ResponseWriter
+	if w.encoder != nil {
+		writer = w.encoder
+	}
+
+	_, err := writer.Write(w.buffer)
+	w.buffer = nil
+	return err
+}
+
+// close writes what's left of the response: the buffered beginning of small responses, or the trailer of the encoding.
+func (w *compressedWriter) close() error {
+	if w.encoder != nil {
+		return w.encoder.Close()
+	}
+
+	if w.passthrough || w.status == 0 {
+		return nil
+	}
+
+	w.pass()
+	return w.flush()
+}
+
+// Compress is an HTTP middleware which compresses responses on the fly if clients accept it.
+// Fast compression levels are used since responses are compressed on every request.
+// Responses smaller than constCompressMin, and responses without bodies, are written as is.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := util.NegotiateEncoding(r, util.EncodingBrotli, util.EncodingGzip)
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writer := &compressedWriter{ResponseWriter: w, encoding: encoding}
+		// nolint:errcheck
+		defer writer.close()
+
+		next.ServeHTTP(writer, r)
+	})
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCompress(t *testing.T) {
+	is := is.New(t)
+	large := strings.Repeat("geojson", constCompressMin)
+
+	tests := []struct {
+		name     string
+		method   string
+		status   int
+		body     string
+		encoding string
+	}{
+		{"large", http.MethodGet, http.StatusOK, large, "gzip"},
+		{"small", http.MethodGet, http.StatusOK, "{}", ""},
+		{"empty", http.MethodGet, http.StatusOK, "", ""},
+		{"no content", http.MethodGet, http.StatusNoContent, "", ""},
+		{"not modified", http.MethodGet, http.StatusNotModified, "", ""},
+		{"head", http.MethodHead, http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			// large bodies are written in parts
+			for i := 0; i < len(tt.body); i += 100 {
+				end := i + 100
+				if end > len(tt.body) {
+					end = len(tt.body)
+				}
+
+				w.Write([]byte(tt.body[i:end])) // nolint:errcheck
+			}
+		}))
+
+		r := httptest.NewRequest(tt.method, "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		is.Equal(w.Code, tt.status)                               // tt.name
+		is.Equal(w.Header().Get("Content-Encoding"), tt.encoding) // tt.name
+		if tt.encoding == "" {
+			is.Equal(w.Body.String(), tt.body) // written as is
+			continue
+		}
+
+		reader, err := gzip.NewReader(w.Body)
+		is.NoErr(err)
+		data, err := ioutil.ReadAll(reader)
+		is.NoErr(err)
+		is.Equal(string(data), tt.body)
+	}
+}
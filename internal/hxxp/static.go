@@ -0,0 +1,122 @@
+package hxxp
+
+import (
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/hiendv/geojson/internal/osm"
+	"github.com/hiendv/geojson/pkg/util"
+	"github.com/julienschmidt/httprouter"
+)
+
+// staticTypes are the content types of outputs, which are needed because compressed sidecars can't be sniffed.
+var staticTypes = map[string]string{
+	".geojson": "application/geo+json",
+	".json":    "application/json",
+}
+
+// outputFS hides internal files of the output directory, e.g. checksums and manifests, from requests and listings.
+type outputFS struct {
+	http.FileSystem
+}
+
+// Open implements http.FileSystem.
+func (fs outputFS) Open(name string) (http.File, error) {
+	if osm.Internal(name) {
+		return nil, os.ErrNotExist
+	}
+
+	f, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return outputFile{f}, nil
+}
+
+type outputFile struct {
+	http.File
+}
+
+// Readdir implements http.File.
+func (f outputFile) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := f.File.Readdir(count)
+	visible := make([]os.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		if osm.Internal(info.Name()) {
+			continue
+		}
+
+		visible = append(visible, info)
+	}
+
+	return visible, err
+}
+
+// serveStatic serves files of a directory the way httprouter.ServeFiles does.
+// Compressed sidecars, e.g. "<file>.br", are served instead if clients accept them and they are up-to-date.
+// Internal files are hidden.
+func serveStatic(dir string) httprouter.Handle {
+	root := outputFS{http.Dir(dir)}
+	fileServer := http.FileServer(root)
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		name := params.ByName("filepath")
+		contentType, ok := staticTypes[path.Ext(name)]
+		if ok {
+			w.Header().Set("Content-Type", contentType)
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if serveCompressed(w, r, root, name) {
+			return
+		}
+
+		r.URL.Path = name
+		fileServer.ServeHTTP(w, r)
+	}
+}
+
+// serveCompressed serves the most preferred compressed sidecar of a file if any.
+func serveCompressed(w http.ResponseWriter, r *http.Request, root http.FileSystem, name string) bool {
+	original, err := stat(root, name)
+	if err != nil || original.IsDir() {
+		return false
+	}
+
+	offers := []string{}
+	for _, encoding := range []string{util.EncodingBrotli, util.EncodingGzip} {
+		sidecar, err := stat(root, name+util.EncodingExtension(encoding))
+		// sidecars are written after the original
+		if err != nil || sidecar.ModTime().Before(original.ModTime()) {
+			continue
+		}
+
+		offers = append(offers, encoding)
+	}
+
+	encoding := util.NegotiateEncoding(r, offers...)
+	if encoding == "" {
+		return false
+	}
+
+	f, err := root.Open(name + util.EncodingExtension(encoding))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Encoding", encoding)
+	http.ServeContent(w, r, name, original.ModTime(), f)
+	return true
+}
+
+func stat(root http.FileSystem, name string) (os.FileInfo, error) {
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.Stat()
+}
@@ -0,0 +1,199 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// ResponseCache keeps full-relation responses of the OpenStreetMap API on disk, e.g. "<dir>/relation/962876/12.osm".
+// Responses are fresh for the TTL, then fetched again: moved nodes change neither their ways nor the relation, so versions can't tell stale responses cheaply.
+// Snapshots never change, so they are kept by their time without expiring, e.g. "<dir>/snapshot/relation/962876/20200101T000000Z.osm".
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// cacheIndex points to the latest version of an object in the cache.
+type cacheIndex struct {
+	Version   int       `json:"version"`
+	Validated time.Time `json:"validated"`
+}
+
+// NewResponseCache constructs a cache in the directory.
+func NewResponseCache(dir string, ttl time.Duration) (*ResponseCache, error) {
+	if dir == "" {
+		return nil, errors.New("invalid cache directory")
+	}
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseCache{dir: dir, ttl: ttl}, nil
+}
+
+func (cache *ResponseCache) objectDir(t osm.Type, id int64) string {
+	return filepath.Join(cache.dir, string(t), fmt.Sprint(id))
+}
+
+func (cache *ResponseCache) indexPath(t osm.Type, id int64) string {
+	return filepath.Join(cache.objectDir(t, id), "index.json")
+}
+
+func (cache *ResponseCache) responsePath(t osm.Type, id int64, version int) string {
+	return filepath.Join(cache.objectDir(t, id), fmt.Sprintf("%d.osm", version))
+}
+
+func (cache *ResponseCache) snapshotPath(t osm.Type, id int64, at time.Time) string {
+	return filepath.Join(cache.dir, "snapshot", string(t), fmt.Sprint(id), at.UTC().Format("20060102T150405Z")+".osm")
+}
+
+func (cache *ResponseCache) index(t osm.Type, id int64) (cacheIndex, bool) {
+	index := cacheIndex{}
+	data, err := ioutil.ReadFile(cache.indexPath(t, id))
+	if err != nil {
+		return index, false
+	}
+
+	return index, json.Unmarshal(data, &index) == nil
+}
+
+func (cache *ResponseCache) writeIndex(t osm.Type, id int64, index cacheIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(cache.indexPath(t, id), data)
+}
+
+func (cache *ResponseCache) read(path string) (*osm.OSM, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &osm.OSM{}
+	err = xml.Unmarshal(data, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func (cache *ResponseCache) write(path string, o *osm.OSM) error {
+	data, err := xml.Marshal(o)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(path, data)
+}
+
+// writeResponse writes the response of an object at its version along with the index pointing to it.
+func (cache *ResponseCache) writeResponse(t osm.Type, id int64, version int, o *osm.OSM) error {
+	err := cache.write(cache.responsePath(t, id, version), o)
+	if err != nil {
+		return err
+	}
+
+	// the index is written last so it never points to a missing response
+	return cache.writeIndex(t, id, cacheIndex{Version: version, Validated: time.Now().UTC()})
+}
+
+// remove drops an object from the cache along with its responses so it's fetched again, e.g. once it's changed upstream.
+// Snapshots are left intact since they never change.
+func (cache *ResponseCache) remove(t osm.Type, id int64) {
+	if cache == nil {
+		return
+	}
+
+	// nolint:errcheck
+	os.RemoveAll(cache.objectDir(t, id))
+}
+
+// relationFull returns the cached full relation, fetching it again once the TTL expires.
+func (cache *ResponseCache) relationFull(ctx context.Context, id int64) (*osm.OSM, error) {
+	log := ctxLog(ctx)
+	index, ok := cache.index(osm.TypeRelation, id)
+	if ok && time.Since(index.Validated) < cache.ttl {
+		o, err := cache.read(cache.responsePath(osm.TypeRelation, id, index.Version))
+		if err == nil {
+			log.Debugw("cache hit", "id", id, "version", index.Version)
+			return o, nil
+		}
+	}
+
+	o, err := fetchRelationFullUncached(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugw("cache miss", "id", id)
+	cache.store(ctx, id, o)
+	return o, nil
+}
+
+// store replaces the cached full relation with a response fetched elsewhere, e.g. a fresher one.
+func (cache *ResponseCache) store(ctx context.Context, id int64, o *osm.OSM) {
+	if cache == nil {
+		return
+	}
+
+	version := 0
+	for _, relation := range o.Relations {
+		if int64(relation.ID) == id {
+			version = relation.Version
+		}
+	}
+
+	err := cache.writeResponse(osm.TypeRelation, id, version, o)
+	if err != nil {
+		ctxLog(ctx).Warnw("cache failed", "id", id, "error", err)
+	}
+}
+
+// relationFullAt returns the cached snapshot of a full relation at the time.
+// Snapshots of the future may still change, so they aren't cached.
+func (cache *ResponseCache) relationFullAt(ctx context.Context, id int64, at time.Time) (*osm.OSM, error) {
+	log := ctxLog(ctx)
+	path := cache.snapshotPath(osm.TypeRelation, id, at)
+	o, err := cache.read(path)
+	if err == nil {
+		log.Debugw("cache hit", "id", id, "at", at)
+		return o, nil
+	}
+
+	o, err = fetchRelationFullAtUncached(ctx, id, at)
+	if err != nil {
+		return nil, err
+	}
+
+	if !at.Before(time.Now()) {
+		return o, nil
+	}
+
+	log.Debugw("cache miss", "id", id, "at", at)
+	err = cache.write(path, o)
+	if err != nil {
+		log.Warnw("cache failed", "id", id, "error", err)
+	}
+
+	return o, nil
+}
@@ -0,0 +1,46 @@
+package osm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+)
+
+func TestResponseCache(t *testing.T) {
+	is := is.New(t)
+	cache, err := NewResponseCache(testDir(t), time.Hour)
+	is.NoErr(err)
+
+	o := &osm.OSM{
+		Relations: osm.Relations{{ID: 1903516, Version: 12}},
+		Ways:      osm.Ways{{ID: 1, Version: 3}, {ID: 2, Version: 7}},
+	}
+	ctx := context.WithValue(context.Background(), ctxKeyLog, &testLogger{})
+	cache.store(ctx, 1903516, o)
+
+	index, ok := cache.index(osm.TypeRelation, 1903516)
+	is.True(ok)
+	is.Equal(index.Version, 12) // responses are kept at the version of the relation
+
+	cached, err := cache.relationFull(ctx, 1903516)
+	is.NoErr(err)
+	is.Equal(len(cached.Ways), 2) // fresh responses are served without requests
+
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	is.NoErr(cache.write(cache.snapshotPath(osm.TypeRelation, 1903516, at), o))
+	cached, err = cache.relationFullAt(ctx, 1903516, at)
+	is.NoErr(err)
+	is.Equal(len(cached.Ways), 2)
+
+	cache.remove(osm.TypeRelation, 1903516)
+	_, ok = cache.index(osm.TypeRelation, 1903516)
+	is.True(!ok)
+	_, err = os.Stat(cache.responsePath(osm.TypeRelation, 1903516, 12))
+	is.True(os.IsNotExist(err)) // responses are removed along with the index
+	_, err = os.Stat(cache.snapshotPath(osm.TypeRelation, 1903516, at))
+	is.NoErr(err) // snapshots never change
+}
@@ -0,0 +1,284 @@
+package osm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// Checkpoint statuses
+const (
+	CheckpointStarted = "started" // the parent was fetched at the version
+	CheckpointDone    = "done"
+	CheckpointFailed  = "failed"
+)
+
+const constPartsExt = ".parts"
+
+// CheckpointEntry is a line of a checkpoint. Entries without an ID belong to the parent itself.
+type CheckpointEntry struct {
+	Parent  int64        `json:"parent"`
+	ID      int64        `json:"id,omitempty"`
+	Version int          `json:"version"`
+	Status  string       `json:"status"`
+	Result  Completeness `json:"result,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// Checkpoint records the progress of a run as JSON lines, so a resumed run skips finished work.
+// Merged sub-areas are kept as parts next to the checkpoint until their parent is done.
+type Checkpoint struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	parents  map[int64]CheckpointEntry
+	subAreas map[int64]map[int64]CheckpointEntry
+}
+
+// OpenCheckpoint starts a checkpoint at the path. Previous progress is loaded if resuming or discarded otherwise.
+func OpenCheckpoint(path string, resume bool) (*Checkpoint, error) {
+	c := &Checkpoint{
+		path:     path,
+		parents:  map[int64]CheckpointEntry{},
+		subAreas: map[int64]map[int64]CheckpointEntry{},
+	}
+
+	if !resume {
+		err := os.RemoveAll(c.path + constPartsExt)
+		if err != nil {
+			return nil, err
+		}
+
+		c.file, err = os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		return c, nil
+	}
+
+	err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// load replays the entries. A line torn by a crash is skipped and terminated.
+func (c *Checkpoint) load() error {
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		entry := CheckpointEntry{}
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+
+		c.apply(entry)
+	}
+
+	if scanner.Err() != nil {
+		return scanner.Err()
+	}
+
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return nil
+	}
+
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte("\n"))
+	return err
+}
+
+func (c *Checkpoint) apply(entry CheckpointEntry) {
+	if entry.ID != 0 {
+		if c.subAreas[entry.Parent] == nil {
+			c.subAreas[entry.Parent] = map[int64]CheckpointEntry{}
+		}
+
+		c.subAreas[entry.Parent][entry.ID] = entry
+		return
+	}
+
+	// members may have changed along with the parent
+	previous, ok := c.parents[entry.Parent]
+	if entry.Status == CheckpointStarted && (!ok || previous.Version != entry.Version) {
+		delete(c.subAreas, entry.Parent)
+	}
+
+	c.parents[entry.Parent] = entry
+}
+
+// record appends an entry and syncs it, so it survives crashes.
+func (c *Checkpoint) record(entry CheckpointEntry) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.apply(entry)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return c.file.Sync()
+}
+
+// Close closes the checkpoint file.
+func (c *Checkpoint) Close() error {
+	if c == nil {
+		return nil
+	}
+
+	return c.file.Close()
+}
+
+// parentDone determines if all sub-areas of a parent were handled and written.
+func (c *Checkpoint) parentDone(parent int64) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.parents[parent].Status == CheckpointDone
+}
+
+// subArea returns a sub-area which was done at the version of its parent.
+func (c *Checkpoint) subArea(parent int64, id int64) (CheckpointEntry, bool) {
+	if c == nil {
+		return CheckpointEntry{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.subAreas[parent][id]
+	return entry, ok && entry.Status == CheckpointDone
+}
+
+// handled lists sub-areas of a parent which were done, e.g. for the report of a skipped parent.
+func (c *Checkpoint) handled(parent int64) []subArea {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	handled := []subArea{}
+	for _, entry := range c.subAreas[parent] {
+		if entry.Status == CheckpointDone {
+			handled = append(handled, subArea{id: entry.ID, version: entry.Version, status: entry.Result})
+		}
+	}
+
+	return handled
+}
+
+func (c *Checkpoint) partPath(parent int64, id int64) string {
+	return filepath.Join(c.path+constPartsExt, fmt.Sprint(parent), fmt.Sprintf("%d.%s", id, constFormat))
+}
+
+// writePart keeps a merged sub-area until its parent is written.
+func (c *Checkpoint) writePart(parent int64, id int64, fc *geojson.FeatureCollection) error {
+	if c == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(fc)
+	if err != nil {
+		return err
+	}
+
+	path := c.partPath(parent, id)
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(path, data)
+}
+
+func (c *Checkpoint) readPart(parent int64, id int64) (*geojson.FeatureCollection, error) {
+	data, err := ioutil.ReadFile(c.partPath(parent, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return geojson.UnmarshalFeatureCollection(data)
+}
+
+// removeParts removes merged sub-areas of a parent which was written.
+func (c *Checkpoint) removeParts(parent int64) error {
+	if c == nil {
+		return nil
+	}
+
+	return os.RemoveAll(filepath.Dir(c.partPath(parent, 0)))
+}
+
+// resumeSubArea restores a sub-area which was done by a previous run.
+// Separated outputs must still be intact while merged ones are read from their parts.
+func resumeSubArea(ctx context.Context, parent int64, id int64) (subArea, bool) {
+	checkpoint := ctxCheckpoint(ctx)
+	entry, ok := checkpoint.subArea(parent, id)
+	if !ok {
+		return subArea{}, false
+	}
+
+	result := subArea{id: id, version: entry.Version, status: entry.Result, resumed: true}
+	if !ctxShouldCombine(ctx) {
+		path, ok := globFilePath(ctx, id)
+		if !ok || verifyChecksum(path) != nil {
+			return subArea{}, false
+		}
+
+		return result, true
+	}
+
+	fc, err := checkpoint.readPart(parent, id)
+	if err != nil {
+		return subArea{}, false
+	}
+
+	result.fc = fc
+	return result, true
+}
@@ -0,0 +1,81 @@
+package osm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	is := is.New(t)
+
+	path := filepath.Join(testDir(t), "checkpoint")
+	checkpoint, err := OpenCheckpoint(path, false)
+	is.NoErr(err)
+	is.NoErr(checkpoint.record(CheckpointEntry{Parent: 1, Version: 3, Status: CheckpointStarted}))
+	is.NoErr(checkpoint.record(CheckpointEntry{Parent: 1, ID: 11, Version: 7, Status: CheckpointDone, Result: CompletenessComplete}))
+	is.NoErr(checkpoint.record(CheckpointEntry{Parent: 1, ID: 12, Version: 2, Status: CheckpointFailed, Error: "timeout"}))
+	is.NoErr(checkpoint.writePart(1, 11, newTestCollection(newTestFeature("relation/11", map[string]string{"name": "Hà Nội"}))))
+	is.NoErr(checkpoint.Close())
+
+	// a line torn by a crash
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	is.NoErr(err)
+	_, err = f.Write([]byte(`{"parent":1,"id":13,"ver`))
+	is.NoErr(err)
+	is.NoErr(f.Close())
+
+	checkpoint, err = OpenCheckpoint(path, true)
+	is.NoErr(err)
+
+	is.True(!checkpoint.parentDone(1))
+	entry, ok := checkpoint.subArea(1, 11)
+	is.True(ok)
+	is.Equal(entry.Result, CompletenessComplete)
+	_, ok = checkpoint.subArea(1, 12)
+	is.True(!ok) // failed sub-areas are retried
+	_, ok = checkpoint.subArea(1, 13)
+	is.True(!ok)
+	is.Equal(checkpoint.handled(1), []subArea{{id: 11, version: 7, status: CompletenessComplete}})
+
+	ctx := CtxSetCheckpoint(newTestContext(&testLogger{}, testDir(t), false, nil), checkpoint)
+	resumed, ok := resumeSubArea(ctx, 1, 11)
+	is.True(ok)
+	is.True(resumed.resumed)
+	is.Equal(len(resumed.fc.Features), 1) // merged sub-areas are read from their parts
+
+	// entries after the torn line are read again
+	is.NoErr(checkpoint.record(CheckpointEntry{Parent: 1, Version: 3, Status: CheckpointDone}))
+	is.NoErr(checkpoint.Close())
+	checkpoint, err = OpenCheckpoint(path, true)
+	is.NoErr(err)
+	is.True(checkpoint.parentDone(1))
+
+	// members may have changed along with the parent
+	is.NoErr(checkpoint.record(CheckpointEntry{Parent: 1, Version: 4, Status: CheckpointStarted}))
+	_, ok = checkpoint.subArea(1, 11)
+	is.True(!ok)
+	is.NoErr(checkpoint.Close())
+
+	// runs which don't resume start over
+	checkpoint, err = OpenCheckpoint(path, false)
+	is.NoErr(err)
+	is.True(!checkpoint.parentDone(1))
+	_, err = checkpoint.readPart(1, 11)
+	is.True(os.IsNotExist(err))
+	is.NoErr(checkpoint.Close())
+}
+
+func TestNilCheckpoint(t *testing.T) {
+	is := is.New(t)
+
+	var checkpoint *Checkpoint
+	is.NoErr(checkpoint.record(CheckpointEntry{Parent: 1}))
+	is.True(!checkpoint.parentDone(1))
+	_, ok := resumeSubArea(context.Background(), 1, 11)
+	is.True(!ok)
+	is.NoErr(checkpoint.Close())
+}
@@ -0,0 +1,129 @@
+package osm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PropertyType is the type a property is coerced into. Names follow JSON Schema.
+type PropertyType string
+
+const (
+	// TypeString leaves values intact.
+	TypeString PropertyType = "string"
+	// TypeInteger parses values as integers, e.g. "admin_level".
+	TypeInteger PropertyType = "integer"
+	// TypeNumber parses values as finite floats, e.g. "area".
+	TypeNumber PropertyType = "number"
+	// TypeBoolean parses values as booleans, including OpenStreetMap "yes" and "no".
+	TypeBoolean PropertyType = "boolean"
+	// TypeArray splits values by semicolons, following OpenStreetMap lists.
+	TypeArray PropertyType = "array"
+)
+
+// ParsePropertyType interprets a string as a PropertyType.
+func ParsePropertyType(str string) (PropertyType, error) {
+	switch kind := PropertyType(str); kind {
+	case TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeArray:
+		return kind, nil
+	default:
+		return TypeString, fmt.Errorf("invalid property type: %s", str)
+	}
+}
+
+// CoercionIssue is a value which could not be coerced.
+type CoercionIssue struct {
+	Property string       `json:"property"`
+	Value    string       `json:"value"`
+	Type     PropertyType `json:"type"`
+}
+
+func (issue CoercionIssue) String() string {
+	return fmt.Sprintf("%s: %q is not %s", issue.Property, issue.Value, issue.Type)
+}
+
+// coerce converts a tag value into the type.
+func (kind PropertyType) coerce(value string) (interface{}, bool) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case TypeInteger:
+		v, err := strconv.ParseInt(value, 10, 64)
+		return v, err == nil
+	case TypeNumber:
+		// JSON has no representation of NaN and infinities, which ParseFloat accepts
+		v, err := strconv.ParseFloat(value, 64)
+		return v, err == nil && !math.IsNaN(v) && !math.IsInf(v, 0)
+	case TypeBoolean:
+		switch strings.ToLower(value) {
+		case "yes", "true", "1":
+			return true, true
+		case "no", "false", "0":
+			return false, true
+		}
+
+		return false, false
+	case TypeArray:
+		values := []string{}
+		for _, v := range strings.Split(value, ";") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+
+			values = append(values, v)
+		}
+
+		return values, true
+	}
+
+	return value, true
+}
+
+// coerceProperties converts typed string properties and tags in place.
+// Invalid values are left intact and reported.
+func coerceProperties(properties map[string]interface{}, types map[string]PropertyType) []CoercionIssue {
+	issues := []CoercionIssue{}
+	if len(types) == 0 {
+		return issues
+	}
+
+	for key, value := range properties {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		kind, ok := types[key]
+		if !ok {
+			continue
+		}
+
+		v, ok := kind.coerce(str)
+		if !ok {
+			issues = append(issues, CoercionIssue{Property: key, Value: str, Type: kind})
+			continue
+		}
+
+		properties[key] = v
+	}
+
+	tags, ok := properties[propTags].(map[string]string)
+	if !ok {
+		return issues
+	}
+
+	typed := make(map[string]interface{}, len(tags))
+	for key, value := range tags {
+		typed[key] = value
+	}
+
+	for _, issue := range coerceProperties(typed, types) {
+		issue.Property = fmt.Sprintf("%s.%s", propTags, issue.Property)
+		issues = append(issues, issue)
+	}
+
+	properties[propTags] = typed
+	return issues
+}
@@ -0,0 +1,70 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParsePropertyType(t *testing.T) {
+	is := is.New(t)
+
+	kind, err := ParsePropertyType("integer")
+	is.NoErr(err)
+	is.Equal(kind, TypeInteger)
+
+	_, err = ParsePropertyType("float")
+	is.True(err != nil)
+}
+
+func TestCoerce(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		kind  PropertyType
+		value string
+		want  interface{}
+		ok    bool
+	}{
+		{TypeString, " Hà Nội ", "Hà Nội", true},
+		{TypeInteger, "4", int64(4), true},
+		{TypeInteger, "4.5", int64(0), false},
+		{TypeNumber, "3358.6", 3358.6, true},
+		{TypeNumber, "1e3", 1000.0, true},
+		{TypeNumber, "NaN", nil, false},
+		{TypeNumber, "Inf", nil, false},
+		{TypeNumber, "-infinity", nil, false},
+		{TypeNumber, "1e400", nil, false},
+		{TypeBoolean, "yes", true, true},
+		{TypeBoolean, "No", false, true},
+		{TypeBoolean, "maybe", false, false},
+		{TypeArray, "vi; en;;fr", []string{"vi", "en", "fr"}, true},
+	}
+
+	for _, test := range tests {
+		v, ok := test.kind.coerce(test.value)
+		is.Equal(ok, test.ok) // coerce(test.value)
+		if test.want != nil {
+			is.Equal(v, test.want)
+		}
+	}
+}
+
+func TestCoerceProperties(t *testing.T) {
+	is := is.New(t)
+
+	types := map[string]PropertyType{"admin_level": TypeInteger, "population": TypeNumber}
+	properties := map[string]interface{}{
+		"admin_level": "4",
+		"name":        "Hà Nội",
+		propTags:      map[string]string{"population": "NaN", "admin_level": "4"},
+	}
+
+	issues := coerceProperties(properties, types)
+	is.Equal(issues, []CoercionIssue{{Property: "tags.population", Value: "NaN", Type: TypeNumber}})
+	is.Equal(properties["admin_level"], int64(4))
+	is.Equal(properties["name"], "Hà Nội")
+	is.Equal(properties[propTags], map[string]interface{}{"population": "NaN", "admin_level": int64(4)})
+
+	is.Equal(coerceProperties(properties, nil), []CoercionIssue{})
+}
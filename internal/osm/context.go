@@ -3,23 +3,53 @@ package osm
 import (
 	"context"
 	"errors"
-	"os"
+	"time"
 
 	"github.com/hiendv/geojson/internal/shared"
+	"github.com/hiendv/geojson/pkg/util"
 	"github.com/paulmach/osm"
+	"golang.org/x/time/rate"
 )
 
 type ctxKey string
 
 const (
-	ctxKeyRaw       ctxKey = "raw"
-	ctxKeySeparated ctxKey = "separated"
-	ctxKeyOut       ctxKey = "out"
-	ctxKeyRewind    ctxKey = "rewind"
-	ctxKeyRoot      ctxKey = "root"
-	ctxKeyLog       ctxKey = "log"
+	ctxKeyRaw        ctxKey = "raw"
+	ctxKeySeparated  ctxKey = "separated"
+	ctxKeyOut        ctxKey = "out"
+	ctxKeyRewind     ctxKey = "rewind"
+	ctxKeyRoot       ctxKey = "root"
+	ctxKeyLog        ctxKey = "log"
+	ctxKeyPrecision  ctxKey = "precision"
+	ctxKeyValidate   ctxKey = "validate"
+	ctxKeyCentroid   ctxKey = "centroid"
+	ctxKeyLabel      ctxKey = "label-point"
+	ctxKeyMeasure    ctxKey = "measure"
+	ctxKeyTags       ctxKey = "tags"
+	ctxKeyNormalize  ctxKey = "normalize"
+	ctxKeyLang       ctxKey = "lang"
+	ctxKeyNormalizer ctxKey = "normalizer"
+	ctxKeyMapping    ctxKey = "mapping"
+	ctxKeyMeta       ctxKey = "meta"
+	ctxKeyID         ctxKey = "id"
+	ctxKeySort       ctxKey = "sort"
+	ctxKeyFailures   ctxKey = "allow-failures"
+	ctxKeyReport     ctxKey = "report"
+	ctxKeyTemplate   ctxKey = "template"
+	ctxKeyCompress   ctxKey = "compress"
+	ctxKeyUpstream   ctxKey = "upstream-rate"
+	ctxKeyCheckpoint ctxKey = "checkpoint"
+	ctxKeyCache      ctxKey = "cache"
+	ctxKeyUpdate     ctxKey = "update"
+	ctxKeyPrevious   ctxKey = "previous"
+	ctxKeyAt         ctxKey = "at"
+	ctxKeyVersion    ctxKey = "version"
+	ctxKeyHistory    ctxKey = "history"
 )
 
+// ctxOptionalKeys are the keys which are not required by NewContext but should survive CtxBareClone.
+var ctxOptionalKeys = []ctxKey{ctxKeyPrecision, ctxKeyValidate, ctxKeyCentroid, ctxKeyLabel, ctxKeyMeasure, ctxKeyTags, ctxKeyNormalize, ctxKeyLang, ctxKeyNormalizer, ctxKeyMapping, ctxKeyMeta, ctxKeyID, ctxKeySort, ctxKeyFailures, ctxKeyReport, ctxKeyTemplate, ctxKeyCompress, ctxKeyUpstream, ctxKeyCheckpoint, ctxKeyCache, ctxKeyUpdate, ctxKeyAt, ctxKeyVersion, ctxKeyHistory} // slice isn't immutable by nature
+
 // NewContext is the utility to encapsulate pkg-scoped context values by preventing context key collision.
 func NewContext(ctx context.Context, log shared.Logger, raw bool, separated bool, out string, rewind bool) (context.Context, error) {
 	ctxx := map[ctxKey]interface{}{
@@ -34,11 +64,7 @@ func NewContext(ctx context.Context, log shared.Logger, raw bool, separated bool
 		log.Debugw("context", "values", ctxx)
 	}
 
-	err := validateOut(out)
-	if os.IsNotExist(err) {
-		err = os.Mkdir(out, 0o700)
-	}
-
+	err := prepareOut(out)
 	if err != nil {
 		return ctx, err
 	}
@@ -55,9 +81,16 @@ func ctxShouldNormalize(ctx context.Context) bool {
 	return !(ok && raw)
 }
 
+// ctxShouldMeta determines if metadata is added, which is always the case for snapshots.
+func ctxShouldMeta(ctx context.Context) bool {
+	_, isSnapshot := ctxSnapshot(ctx)
+	meta, ok := ctx.Value(ctxKeyMeta).(bool)
+	return (ok && meta) || isSnapshot
+}
+
 func ctxShouldPrint(ctx context.Context) bool {
 	out, ok := ctx.Value(ctxKeyOut).(string)
-	return ok && out == ""
+	return ok && (out == "" || out == constStdout)
 }
 
 func ctxShouldCombine(ctx context.Context) bool {
@@ -75,6 +108,215 @@ func ctxShouldRewind(ctx context.Context) bool {
 	return ok && rewind
 }
 
+func ctxPrecision(ctx context.Context) (int, bool) {
+	v, ok := ctx.Value(ctxKeyPrecision).(int)
+	return v, ok && v >= 0
+}
+
+func ctxValidation(ctx context.Context) ValidationMode {
+	v, ok := ctx.Value(ctxKeyValidate).(ValidationMode)
+	if !ok {
+		return ValidationNone
+	}
+
+	return v
+}
+
+func ctxShouldCentroid(ctx context.Context) bool {
+	centroid, ok := ctx.Value(ctxKeyCentroid).(bool)
+	return ok && centroid
+}
+
+func ctxShouldLabelPoint(ctx context.Context) bool {
+	label, ok := ctx.Value(ctxKeyLabel).(bool)
+	return ok && label
+}
+
+func ctxMeasure(ctx context.Context) MeasureUnit {
+	v, ok := ctx.Value(ctxKeyMeasure).(MeasureUnit)
+	if !ok {
+		return MeasureNone
+	}
+
+	return v
+}
+
+func ctxTags(ctx context.Context) TagFilter {
+	v, ok := ctx.Value(ctxKeyTags).(TagFilter)
+	if !ok || v.Empty() {
+		return TagFilter{patterns: constTags}
+	}
+
+	return v
+}
+
+func ctxNormalizedTags(ctx context.Context) TagFilter {
+	v, ok := ctx.Value(ctxKeyNormalize).(TagFilter)
+	if !ok || v.Empty() {
+		return TagFilter{all: true}
+	}
+
+	return v
+}
+
+// namedNormalizer keeps the form of a normalizer so outputs record it in their manifests.
+type namedNormalizer struct {
+	form       string
+	normalizer util.Normalizer
+}
+
+func ctxNormalizer(ctx context.Context) util.Normalizer {
+	v, ok := ctx.Value(ctxKeyNormalizer).(namedNormalizer)
+	if !ok || v.normalizer == nil {
+		return func(str, _ string) string {
+			return util.NormalizeString(str)
+		}
+	}
+
+	return v.normalizer
+}
+
+func ctxNormalizerForm(ctx context.Context) string {
+	v, ok := ctx.Value(ctxKeyNormalizer).(namedNormalizer)
+	if !ok || v.form == "" {
+		return util.NormalizerStrip
+	}
+
+	return v.form
+}
+
+func ctxMapping(ctx context.Context) (*Mapping, bool) {
+	v, ok := ctx.Value(ctxKeyMapping).(*Mapping)
+	if !ok || v == nil {
+		return nil, false
+	}
+
+	return v, true
+}
+
+func ctxIDFormat(ctx context.Context) IDFormat {
+	v, ok := ctx.Value(ctxKeyID).(IDFormat)
+	if !ok {
+		return IDOSM
+	}
+
+	return v
+}
+
+func ctxSort(ctx context.Context) string {
+	v, ok := ctx.Value(ctxKeySort).(string)
+	if !ok {
+		return ""
+	}
+
+	return v
+}
+
+// ctxAllowedFailures returns the number of sub-areas allowed to fail. Negative values mean unlimited.
+func ctxAllowedFailures(ctx context.Context) (int, bool) {
+	v, ok := ctx.Value(ctxKeyFailures).(int)
+	if !ok || v < 0 {
+		return 0, false
+	}
+
+	return v, true
+}
+
+func ctxReport(ctx context.Context) string {
+	v, ok := ctx.Value(ctxKeyReport).(string)
+	if !ok {
+		return ""
+	}
+
+	return v
+}
+
+func ctxTemplate(ctx context.Context) Template {
+	v, ok := ctx.Value(ctxKeyTemplate).(Template)
+	if !ok || v == "" {
+		return constTemplate
+	}
+
+	return v
+}
+
+func ctxCompression(ctx context.Context) []string {
+	v, ok := ctx.Value(ctxKeyCompress).([]string)
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
+// ctxUpstreamLimiter returns the limiter shared by requests to the upstream API. Nil means unlimited.
+func ctxUpstreamLimiter(ctx context.Context) *rate.Limiter {
+	v, ok := ctx.Value(ctxKeyUpstream).(*rate.Limiter)
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
+// ctxCheckpoint returns the checkpoint of the run. Nil means no checkpointing.
+func ctxCheckpoint(ctx context.Context) *Checkpoint {
+	v, ok := ctx.Value(ctxKeyCheckpoint).(*Checkpoint)
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
+// ctxResponseCache returns the cache of upstream responses. Nil means no caching.
+func ctxResponseCache(ctx context.Context) *ResponseCache {
+	v, ok := ctx.Value(ctxKeyCache).(*ResponseCache)
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
+func ctxShouldUpdate(ctx context.Context) bool {
+	update, ok := ctx.Value(ctxKeyUpdate).(bool)
+	return ok && update
+}
+
+// ctxPreviousOutput returns the merged output of the parent which was written by a previous run.
+func ctxPreviousOutput(ctx context.Context) (*previousOutput, bool) {
+	v, ok := ctx.Value(ctxKeyPrevious).(*previousOutput)
+	return v, ok && v != nil
+}
+
+// ctxSnapshot returns the time of a historical snapshot.
+func ctxSnapshot(ctx context.Context) (time.Time, bool) {
+	v, ok := ctx.Value(ctxKeyAt).(time.Time)
+	return v, ok && !v.IsZero()
+}
+
+// ctxSnapshotVersion returns the version of the parent of a historical snapshot.
+func ctxSnapshotVersion(ctx context.Context) (int, bool) {
+	v, ok := ctx.Value(ctxKeyVersion).(int)
+	return v, ok && v > 0
+}
+
+// ctxHistory returns the full-history file snapshots are built from. False means the history API.
+func ctxHistory(ctx context.Context) (*History, bool) {
+	v, ok := ctx.Value(ctxKeyHistory).(*History)
+	return v, ok && v != nil
+}
+
+func ctxLanguages(ctx context.Context) []string {
+	v, ok := ctx.Value(ctxKeyLang).([]string)
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
 func ctxRoot(ctx context.Context) (*osm.Relation, bool) {
 	v, ok := ctx.Value(ctxKeyRoot).(*osm.Relation)
 	return v, ok
@@ -94,6 +336,151 @@ func CtxSetRewind(ctx context.Context, rewind bool) context.Context {
 	return context.WithValue(ctx, ctxKeyRewind, rewind)
 }
 
+// CtxSetPrecision sets "precision" value to this context.
+// Negative values disable coordinate rounding.
+func CtxSetPrecision(ctx context.Context, precision int) context.Context {
+	return context.WithValue(ctx, ctxKeyPrecision, precision)
+}
+
+// CtxSetValidation sets "validate" value to this context.
+func CtxSetValidation(ctx context.Context, mode ValidationMode) context.Context {
+	return context.WithValue(ctx, ctxKeyValidate, mode)
+}
+
+// CtxSetCentroid sets "centroid" value to this context.
+func CtxSetCentroid(ctx context.Context, centroid bool) context.Context {
+	return context.WithValue(ctx, ctxKeyCentroid, centroid)
+}
+
+// CtxSetLabelPoint sets "label-point" value to this context.
+func CtxSetLabelPoint(ctx context.Context, label bool) context.Context {
+	return context.WithValue(ctx, ctxKeyLabel, label)
+}
+
+// CtxSetMeasure sets "measure" value to this context.
+func CtxSetMeasure(ctx context.Context, unit MeasureUnit) context.Context {
+	return context.WithValue(ctx, ctxKeyMeasure, unit)
+}
+
+// CtxSetTags sets "tags" value to this context.
+// Tags which aren't matched by the filter are dropped.
+func CtxSetTags(ctx context.Context, filter TagFilter) context.Context {
+	return context.WithValue(ctx, ctxKeyTags, filter)
+}
+
+// CtxSetNormalizedTags sets "normalize" value to this context.
+// Only tags matched by the filter are normalized.
+func CtxSetNormalizedTags(ctx context.Context, filter TagFilter) context.Context {
+	return context.WithValue(ctx, ctxKeyNormalize, filter)
+}
+
+// CtxSetLanguages sets "lang" value to this context.
+// Languages are in the order of preference and should be canonicalized by util.ParseLanguages.
+func CtxSetLanguages(ctx context.Context, langs []string) context.Context {
+	return context.WithValue(ctx, ctxKeyLang, langs)
+}
+
+// CtxSetNormalizer sets "normalizer" value to this context.
+// The form is parsed by util.ParseNormalizer.
+func CtxSetNormalizer(ctx context.Context, form string) (context.Context, error) {
+	normalizer, err := util.ParseNormalizer(form)
+	if err != nil {
+		return ctx, err
+	}
+
+	return context.WithValue(ctx, ctxKeyNormalizer, namedNormalizer{form: form, normalizer: normalizer}), nil
+}
+
+// CtxSetMapping sets "mapping" value to this context.
+// A nil mapping keeps the tags as they are.
+func CtxSetMapping(ctx context.Context, mapping *Mapping) context.Context {
+	return context.WithValue(ctx, ctxKeyMapping, mapping)
+}
+
+// CtxSetMeta sets "meta" value to this context.
+func CtxSetMeta(ctx context.Context, meta bool) context.Context {
+	return context.WithValue(ctx, ctxKeyMeta, meta)
+}
+
+// CtxSetIDFormat sets "id" value to this context.
+func CtxSetIDFormat(ctx context.Context, format IDFormat) context.Context {
+	return context.WithValue(ctx, ctxKeyID, format)
+}
+
+// CtxSetSort sets "sort" value to this context.
+// Merged features are ordered by the property, then by their sub-area IDs.
+func CtxSetSort(ctx context.Context, property string) context.Context {
+	return context.WithValue(ctx, ctxKeySort, property)
+}
+
+// CtxSetAllowedFailures sets "allow-failures" value to this context.
+// SubAreas returns ErrFailures if more sub-areas fail. Negative values mean unlimited.
+func CtxSetAllowedFailures(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, ctxKeyFailures, n)
+}
+
+// CtxSetReport sets "report" value to this context.
+// SubAreas writes a JSON report to the path if it's not empty.
+func CtxSetReport(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, ctxKeyReport, path)
+}
+
+// CtxSetTemplate sets "template" value to this context.
+func CtxSetTemplate(ctx context.Context, template Template) context.Context {
+	return context.WithValue(ctx, ctxKeyTemplate, template)
+}
+
+// CtxSetCompression sets "compress" value to this context.
+// Outputs get a compressed sidecar per encoding, e.g. "<output>.br".
+func CtxSetCompression(ctx context.Context, encodings []string) context.Context {
+	return context.WithValue(ctx, ctxKeyCompress, encodings)
+}
+
+// CtxSetUpstreamRate sets "upstream-rate" value to this context.
+// Requests to the upstream API are limited to rps requests per second across all workers. Non-positive values mean unlimited.
+func CtxSetUpstreamRate(ctx context.Context, rps float64) context.Context {
+	if rps <= 0 {
+		return context.WithValue(ctx, ctxKeyUpstream, (*rate.Limiter)(nil))
+	}
+
+	return context.WithValue(ctx, ctxKeyUpstream, rate.NewLimiter(rate.Limit(rps), 1))
+}
+
+// CtxSetCheckpoint sets "checkpoint" value to this context.
+// SubAreasBatch records its progress to the checkpoint and skips work which is already done.
+func CtxSetCheckpoint(ctx context.Context, checkpoint *Checkpoint) context.Context {
+	return context.WithValue(ctx, ctxKeyCheckpoint, checkpoint)
+}
+
+// CtxSetResponseCache sets "cache" value to this context.
+// Full relations are read from the cache unless they have changed upstream.
+func CtxSetResponseCache(ctx context.Context, cache *ResponseCache) context.Context {
+	return context.WithValue(ctx, ctxKeyCache, cache)
+}
+
+// CtxSetUpdate sets "update" value to this context.
+// Sub-areas are regenerated only if they, their member ways or their nodes have changed since the previous output, or if it was built with other options.
+func CtxSetUpdate(ctx context.Context, update bool) context.Context {
+	return context.WithValue(ctx, ctxKeyUpdate, update)
+}
+
+func ctxSetPreviousOutput(ctx context.Context, previous *previousOutput) context.Context {
+	return context.WithValue(ctx, ctxKeyPrevious, previous)
+}
+
+// CtxSetSnapshot sets "at" and "version" values to this context.
+// Parents and their members are fetched as they were at the time, or at the version of the parent. Zero values mean the current data.
+func CtxSetSnapshot(ctx context.Context, at time.Time, version int) context.Context {
+	ctx = context.WithValue(ctx, ctxKeyAt, at)
+	return context.WithValue(ctx, ctxKeyVersion, version)
+}
+
+// CtxSetHistory sets "history" value to this context.
+// Snapshots are built from the full-history file instead of the history API.
+func CtxSetHistory(ctx context.Context, history *History) context.Context {
+	return context.WithValue(ctx, ctxKeyHistory, history)
+}
+
 // CtxSetRoot sets "root" value to this context.
 func CtxSetRoot(ctx context.Context, root *osm.Relation) context.Context {
 	return context.WithValue(ctx, ctxKeyRoot, root)
@@ -127,5 +514,19 @@ func CtxBareClone(ctx context.Context) (context.Context, error) {
 		return ctx, errors.New("invalid context: rewind")
 	}
 
-	return NewContext(context.Background(), log.Clone(), raw, separated, out, rewind)
+	clone, err := NewContext(context.Background(), log.Clone(), raw, separated, out, rewind)
+	if err != nil {
+		return clone, err
+	}
+
+	for _, key := range ctxOptionalKeys {
+		v := ctx.Value(key)
+		if v == nil {
+			continue
+		}
+
+		clone = context.WithValue(clone, key, v)
+	}
+
+	return clone, nil
 }
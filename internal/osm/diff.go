@@ -0,0 +1,331 @@
+package osm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+
+	"github.com/hiendv/geojson/pkg/geoutil"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// DiffFormat is the format of a diff report.
+type DiffFormat string
+
+// Diff formats
+const (
+	DiffText    DiffFormat = "text"
+	DiffJSON    DiffFormat = "json"
+	DiffGeoJSON DiffFormat = "geojson" // features which were added, removed or changed
+)
+
+// ParseDiffFormat validates a diff format. An empty format means text.
+func ParseDiffFormat(str string) (DiffFormat, error) {
+	switch DiffFormat(str) {
+	case "":
+		return DiffText, nil
+	case DiffText, DiffJSON, DiffGeoJSON:
+		return DiffFormat(str), nil
+	}
+
+	return "", fmt.Errorf("invalid diff format: %s", str)
+}
+
+// DiffReport lists the differences of two outputs whose features are matched by their IDs.
+type DiffReport struct {
+	Added     []FeatureDiff `json:"added"`
+	Removed   []FeatureDiff `json:"removed"`
+	Changed   []FeatureDiff `json:"changed"`
+	Unchanged int           `json:"unchanged"`
+}
+
+// FeatureDiff is the difference of a feature.
+type FeatureDiff struct {
+	ID         interface{}      `json:"id"`
+	Name       string           `json:"name,omitempty"`
+	OldName    string           `json:"old_name,omitempty"` // the feature was renamed
+	Properties []PropertyChange `json:"properties,omitempty"`
+	Geometry   *GeometryChange  `json:"geometry,omitempty"`
+
+	old *geojson.Feature
+	new *geojson.Feature
+}
+
+// PropertyChange is the difference of a property. Nested properties are keyed by their paths, e.g. "tags.name".
+// Missing values are null.
+type PropertyChange struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// GeometryChange measures the difference of a geometry.
+// Geometries which changed but can't be measured, e.g. empty ones, come with the error instead.
+type GeometryChange struct {
+	AreaDelta float64 `json:"area_delta"` // square meters, zero for geometries without areas
+	Hausdorff float64 `json:"hausdorff"`  // meters
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadFeatureCollection reads a GeoJSON output.
+func ReadFeatureCollection(path string) (*geojson.FeatureCollection, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return geojson.UnmarshalFeatureCollection(data)
+}
+
+// Diff compares two outputs by the IDs of their features.
+func Diff(previous, current *geojson.FeatureCollection) (*DiffReport, error) {
+	oldFeatures, err := featuresByID(previous)
+	if err != nil {
+		return nil, fmt.Errorf("old: %w", err)
+	}
+
+	newFeatures, err := featuresByID(current)
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+
+	report := &DiffReport{Added: []FeatureDiff{}, Removed: []FeatureDiff{}, Changed: []FeatureDiff{}}
+	for _, before := range previous.Features {
+		after, ok := newFeatures[fmt.Sprint(before.ID)]
+		if !ok {
+			report.Removed = append(report.Removed, FeatureDiff{ID: before.ID, Name: featureName(before), old: before})
+			continue
+		}
+
+		diff, changed := diffFeature(before, after)
+		if !changed {
+			report.Unchanged++
+			continue
+		}
+
+		report.Changed = append(report.Changed, diff)
+	}
+
+	for _, after := range current.Features {
+		_, ok := oldFeatures[fmt.Sprint(after.ID)]
+		if !ok {
+			report.Added = append(report.Added, FeatureDiff{ID: after.ID, Name: featureName(after), new: after})
+		}
+	}
+
+	return report, nil
+}
+
+// featuresByID indexes features by their IDs, which must be unique.
+func featuresByID(fc *geojson.FeatureCollection) (map[string]*geojson.Feature, error) {
+	if fc == nil {
+		return nil, errors.New("invalid feature collection")
+	}
+
+	features := map[string]*geojson.Feature{}
+	for _, feature := range fc.Features {
+		if feature.ID == nil {
+			return nil, errors.New("features without IDs can't be matched")
+		}
+
+		key := fmt.Sprint(feature.ID)
+		if features[key] != nil {
+			return nil, fmt.Errorf("duplicate feature ID: %s", key)
+		}
+
+		features[key] = feature
+	}
+
+	return features, nil
+}
+
+func featureName(feature *geojson.Feature) string {
+	name, ok := featureProperty(feature, "name")
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprint(name)
+}
+
+func diffFeature(before, after *geojson.Feature) (FeatureDiff, bool) {
+	diff := FeatureDiff{ID: after.ID, Name: featureName(after), old: before, new: after}
+	if name := featureName(before); name != diff.Name {
+		diff.OldName = name
+	}
+
+	diff.Properties = diffProperties(flattenProperties(before.Properties), flattenProperties(after.Properties))
+	if !orb.Equal(before.Geometry, after.Geometry) {
+		change, err := diffGeometry(before.Geometry, after.Geometry)
+		if err != nil {
+			// a feature which can't be measured doesn't stop the others from being compared
+			change = &GeometryChange{Error: err.Error()}
+		}
+
+		diff.Geometry = change
+	}
+
+	return diff, len(diff.Properties) != 0 || diff.Geometry != nil
+}
+
+// flattenProperties keys nested properties by their paths at any depth, e.g. "tags.name" or "meta.parent.id".
+// Arrays are compared as a whole.
+func flattenProperties(properties geojson.Properties) map[string]interface{} {
+	flat := map[string]interface{}{}
+	for key, value := range properties {
+		flattenProperty(flat, key, value)
+	}
+
+	return flat
+}
+
+func flattenProperty(flat map[string]interface{}, path string, value interface{}) {
+	nested, ok := value.(map[string]interface{})
+	if !ok || len(nested) == 0 {
+		flat[path] = value
+		return
+	}
+
+	for key, v := range nested {
+		flattenProperty(flat, path+"."+key, v)
+	}
+}
+
+func diffProperties(before, after map[string]interface{}) []PropertyChange {
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+
+	for key := range after {
+		keys[key] = true
+	}
+
+	changes := []PropertyChange{}
+	for key := range keys {
+		if !reflect.DeepEqual(before[key], after[key]) {
+			changes = append(changes, PropertyChange{Key: key, Old: before[key], New: after[key]})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
+
+func diffGeometry(before, after orb.Geometry) (*GeometryChange, error) {
+	hausdorff, err := geoutil.HausdorffDistance(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &GeometryChange{Hausdorff: hausdorff}
+	areaBefore, errBefore := geoutil.GeodesicArea(before)
+	areaAfter, errAfter := geoutil.GeodesicArea(after)
+	if errBefore == nil && errAfter == nil {
+		change.AreaDelta = areaAfter - areaBefore
+	}
+
+	return change, nil
+}
+
+// Marshal encodes the report in the format.
+func (report *DiffReport) Marshal(format DiffFormat) ([]byte, error) {
+	switch format {
+	case DiffJSON:
+		return json.MarshalIndent(report, "", "  ")
+	case DiffGeoJSON:
+		return json.Marshal(report.featureCollection())
+	}
+
+	return report.text(), nil
+}
+
+// featureCollection lists the geometries of the differences. Changed geometries come both as "previous" and "modified".
+func (report *DiffReport) featureCollection() *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	appendFeature := func(diff FeatureDiff, feature *geojson.Feature, change string) {
+		f := geojson.NewFeature(feature.Geometry)
+		f.ID = diff.ID
+		f.Properties["change"] = change
+		if diff.Name != "" {
+			f.Properties["name"] = diff.Name
+		}
+
+		if change == "modified" && diff.Geometry.Error != "" {
+			f.Properties["error"] = diff.Geometry.Error
+		} else if change == "modified" {
+			f.Properties["area_delta"] = diff.Geometry.AreaDelta
+			f.Properties["hausdorff"] = diff.Geometry.Hausdorff
+		}
+
+		fc.Append(f)
+	}
+
+	for _, diff := range report.Added {
+		appendFeature(diff, diff.new, "added")
+	}
+
+	for _, diff := range report.Removed {
+		appendFeature(diff, diff.old, "removed")
+	}
+
+	for _, diff := range report.Changed {
+		if diff.Geometry == nil {
+			appendFeature(diff, diff.new, "properties")
+			continue
+		}
+
+		appendFeature(diff, diff.old, "previous")
+		appendFeature(diff, diff.new, "modified")
+	}
+
+	return fc
+}
+
+func (report *DiffReport) text() []byte {
+	var b bytes.Buffer
+	for _, diff := range report.Added {
+		fmt.Fprintf(&b, "+ %v %s\n", diff.ID, diff.Name)
+	}
+
+	for _, diff := range report.Removed {
+		fmt.Fprintf(&b, "- %v %s\n", diff.ID, diff.Name)
+	}
+
+	for _, diff := range report.Changed {
+		fmt.Fprintf(&b, "~ %v %s\n", diff.ID, diff.Name)
+		if diff.OldName != "" {
+			fmt.Fprintf(&b, "    renamed from %q\n", diff.OldName)
+		}
+
+		for _, change := range diff.Properties {
+			fmt.Fprintf(&b, "    %s: %s -> %s\n", change.Key, textValue(change.Old), textValue(change.New))
+		}
+
+		if diff.Geometry != nil && diff.Geometry.Error != "" {
+			fmt.Fprintf(&b, "    geometry: changed, not measured: %s\n", diff.Geometry.Error)
+		} else if diff.Geometry != nil {
+			fmt.Fprintf(&b, "    geometry: area %+.1f m², hausdorff %.1f m\n", diff.Geometry.AreaDelta, diff.Geometry.Hausdorff)
+		}
+	}
+
+	fmt.Fprintf(&b, "%d added, %d removed, %d changed, %d unchanged\n", len(report.Added), len(report.Removed), len(report.Changed), report.Unchanged)
+	return b.Bytes()
+}
+
+func textValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,159 @@
+package osm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func newTestArea(id string, name string, square orb.Bound) *geojson.Feature {
+	feature := geojson.NewFeature(square.ToPolygon())
+	feature.ID = id
+	feature.Properties["name"] = name
+	return feature
+}
+
+func TestDiff(t *testing.T) {
+	is := is.New(t)
+
+	small := orb.Bound{Min: orb.Point{105, 21}, Max: orb.Point{105.1, 21.1}}
+	large := orb.Bound{Min: orb.Point{105, 21}, Max: orb.Point{105.2, 21.1}}
+
+	broken := newTestArea("relation/4", "Huế", small)
+	broken.Geometry = nil
+
+	previous := newTestCollection(
+		newTestArea("relation/1", "Hà Nội", small),
+		newTestArea("relation/2", "Hà Tây", small),
+		newTestArea("relation/3", "Sài Gòn", small),
+		broken,
+		newTestArea("relation/5", "Đà Nẵng", small),
+	)
+	current := newTestCollection(
+		newTestArea("relation/1", "Hà Nội", large),
+		newTestArea("relation/3", "Thành phố Hồ Chí Minh", small),
+		newTestArea("relation/4", "Huế", small),
+		newTestArea("relation/5", "Đà Nẵng", small),
+		newTestArea("relation/6", "Cần Thơ", small),
+	)
+
+	report, err := Diff(previous, current)
+	is.NoErr(err)
+	is.Equal(report.Unchanged, 1)
+	is.Equal(len(report.Added), 1)
+	is.Equal(report.Added[0].ID, "relation/6")
+	is.Equal(len(report.Removed), 1)
+	is.Equal(report.Removed[0].Name, "Hà Tây")
+	is.Equal(len(report.Changed), 3)
+
+	grown := report.Changed[0]
+	is.Equal(grown.ID, "relation/1")
+	is.True(grown.Geometry.AreaDelta > 0)
+	is.True(grown.Geometry.Hausdorff > 0)
+	is.Equal(len(grown.Properties), 0)
+
+	renamed := report.Changed[1]
+	is.Equal(renamed.OldName, "Sài Gòn")
+	is.Equal(renamed.Properties, []PropertyChange{{Key: "name", Old: "Sài Gòn", New: "Thành phố Hồ Chí Minh"}})
+	is.True(renamed.Geometry == nil)
+
+	// the geometry which can't be measured doesn't fail the diff
+	unmeasured := report.Changed[2]
+	is.Equal(unmeasured.ID, "relation/4")
+	is.True(unmeasured.Geometry.Error != "")
+
+	duplicated := newTestCollection(newTestArea("relation/1", "Hà Nội", small), newTestArea("relation/1", "Hà Nội", large))
+	_, err = Diff(duplicated, current)
+	is.True(err != nil) // features can't be matched
+
+	anonymous := newTestCollection(newTestArea("relation/1", "Hà Nội", small))
+	anonymous.Features[0].ID = nil
+	_, err = Diff(previous, anonymous)
+	is.True(err != nil)
+}
+
+func TestFlattenProperties(t *testing.T) {
+	is := is.New(t)
+
+	properties := geojson.Properties{
+		"name": "Hà Nội",
+		"tags": map[string]interface{}{"name": "Hà Nội", "admin_level": "4"},
+		"meta": map[string]interface{}{
+			"parent": map[string]interface{}{"id": 49915.0, "version": 3.0},
+		},
+		"ways":  []interface{}{1.0, 2.0},
+		"empty": map[string]interface{}{},
+	}
+
+	is.Equal(flattenProperties(properties), map[string]interface{}{
+		"name":                "Hà Nội",
+		"tags.name":           "Hà Nội",
+		"tags.admin_level":    "4",
+		"meta.parent.id":      49915.0,
+		"meta.parent.version": 3.0,
+		"ways":                []interface{}{1.0, 2.0},
+		"empty":               map[string]interface{}{},
+	})
+
+	before := newTestArea("relation/1", "Hà Nội", orb.Bound{})
+	before.Properties["meta"] = map[string]interface{}{"parent": map[string]interface{}{"version": 3.0}}
+	after := newTestArea("relation/1", "Hà Nội", orb.Bound{})
+	after.Properties["meta"] = map[string]interface{}{"parent": map[string]interface{}{"version": 4.0}}
+
+	diff, changed := diffFeature(before, after)
+	is.True(changed)
+	is.Equal(diff.Properties, []PropertyChange{{Key: "meta.parent.version", Old: 3.0, New: 4.0}})
+}
+
+func TestDiffMarshal(t *testing.T) {
+	is := is.New(t)
+
+	small := orb.Bound{Min: orb.Point{105, 21}, Max: orb.Point{105.1, 21.1}}
+	large := orb.Bound{Min: orb.Point{105, 21}, Max: orb.Point{105.2, 21.1}}
+	broken := newTestArea("relation/3", "Huế", small)
+	broken.Geometry = nil
+
+	report, err := Diff(
+		newTestCollection(newTestArea("relation/1", "Hà Nội", small), newTestArea("relation/2", "Hà Tây", small), broken),
+		newTestCollection(newTestArea("relation/1", "Hà Nội", large), newTestArea("relation/3", "Huế", small), newTestArea("relation/4", "Cần Thơ", small)),
+	)
+	is.NoErr(err)
+
+	text, err := report.Marshal(DiffText)
+	is.NoErr(err)
+	is.True(strings.Contains(string(text), "+ relation/4 Cần Thơ\n"))
+	is.True(strings.Contains(string(text), "- relation/2 Hà Tây\n"))
+	is.True(strings.Contains(string(text), "~ relation/1 Hà Nội\n    geometry: area +"))
+	is.True(strings.Contains(string(text), "~ relation/3 Huế\n    geometry: changed, not measured: "))
+	is.True(strings.HasSuffix(string(text), "1 added, 1 removed, 2 changed, 0 unchanged\n"))
+
+	data, err := report.Marshal(DiffJSON)
+	is.NoErr(err)
+	decoded := DiffReport{}
+	is.NoErr(json.Unmarshal(data, &decoded))
+	is.Equal(len(decoded.Changed), 2)
+	is.Equal(decoded.Changed[1].Geometry.Error, report.Changed[1].Geometry.Error)
+
+	data, err = report.Marshal(DiffGeoJSON)
+	is.NoErr(err)
+	// null geometries are valid GeoJSON but orb can't read them
+	fc := struct {
+		Features []struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}{}
+	is.NoErr(json.Unmarshal(data, &fc))
+
+	changes := []interface{}{}
+	for _, feature := range fc.Features {
+		changes = append(changes, feature.Properties["change"])
+	}
+	// changed geometries come both as previous and modified
+	is.Equal(changes, []interface{}{"added", "removed", "previous", "modified", "previous", "modified"})
+	is.True(fc.Features[3].Properties["hausdorff"] != nil)
+	is.True(fc.Features[5].Properties["error"] != nil)
+}
@@ -2,8 +2,15 @@ package osm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hiendv/geojson/pkg/geoutil"
+	"github.com/paulmach/orb/geojson"
 )
 
 func validateOut(path string) error {
@@ -11,11 +18,25 @@ func validateOut(path string) error {
 	return err
 }
 
-// FindSubAreas looks for outputs of a sub-area.
+// prepareOut creates the output directory unless outputs are printed.
+func prepareOut(path string) error {
+	if path == "" || path == constStdout {
+		return nil
+	}
+
+	err := validateOut(path)
+	if os.IsNotExist(err) {
+		err = os.Mkdir(path, 0o700)
+	}
+
+	return err
+}
+
+// FindSubAreas looks for outputs of a sub-area through the naming template.
 func FindSubAreas(ctx context.Context, id int64) (string, error) {
-	path, ok := filePath(ctx, id)
+	path, ok := globFilePath(ctx, id)
 	if !ok {
-		return "", errors.New("invalid directory")
+		return "", errors.New("invalid path")
 	}
 
 	err := VerifyOutput(ctx, path)
@@ -26,12 +47,96 @@ func FindSubAreas(ctx context.Context, id int64) (string, error) {
 	return path, nil
 }
 
-// VerifyOutput makes sure that output files of a sub-area exist.
+// VerifyOutput makes sure that output files of a sub-area exist, are intact and up-to-date.
 func VerifyOutput(ctx context.Context, path string) error {
 	_, err := os.Stat(path)
 	if err != nil {
 		return errors.New("invalid path")
 	}
 
+	if isStale(path) {
+		ctxLog(ctx).Debugw("stale output", "path", path)
+		return errors.New("stale output")
+	}
+
+	err = verifyChecksum(path)
+	if errors.Is(err, errMissingChecksum) {
+		err = adoptOutput(path)
+		if err == nil {
+			ctxLog(ctx).Infow("output adopted", "path", path)
+		}
+	}
+
+	if err != nil {
+		ctxLog(ctx).Warnw("corrupted output", "path", path, "error", err)
+		return errors.New("corrupted output")
+	}
+
 	return nil
 }
+
+// Internal determines if a file of the output directory is only meant for this tool: temporary files, manifests, checksums, stale markers and dotfiles.
+// Compressed sidecars are public.
+func Internal(name string) bool {
+	for _, segment := range strings.Split(filepath.ToSlash(name), "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+
+	for _, ext := range []string{constTmpExt, constChecksumExt, constManifestExt, constStaleExt} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Extent is the bounding box of a sub-area output without its geometry.
+type Extent struct {
+	BBox     geojson.BBox    `json:"bbox"`
+	Features []FeatureExtent `json:"features"`
+}
+
+// FeatureExtent is the bounding box of a feature without its geometry.
+type FeatureExtent struct {
+	ID   interface{}  `json:"id"`
+	BBox geojson.BBox `json:"bbox"`
+}
+
+// ReadExtent reads bounding boxes of an output.
+// Boxes are computed from the geometry if the output doesn't have them.
+func ReadExtent(ctx context.Context, path string) (*Extent, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	extent := &Extent{}
+	err = json.Unmarshal(data, extent)
+	if err != nil {
+		return nil, err
+	}
+
+	if extent.BBox != nil || len(extent.Features) == 0 {
+		return extent, nil
+	}
+
+	featureCollection, err := geojson.UnmarshalFeatureCollection(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = geoutil.BBoxFeatureCollection(featureCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	extent.BBox = featureCollection.BBox
+	for i, feature := range featureCollection.Features {
+		extent.Features[i].BBox = feature.BBox
+	}
+
+	return extent, nil
+}
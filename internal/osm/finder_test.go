@@ -0,0 +1,70 @@
+package osm
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestVerifyOutput(t *testing.T) {
+	is := is.New(t)
+	ctx := context.WithValue(context.Background(), ctxKeyLog, &testLogger{})
+
+	tests := []struct {
+		name     string
+		data     string
+		manifest bool
+		ok       bool
+	}{
+		{"legacy.geojson", `{"type":"FeatureCollection","features":[]}`, false, true},
+		{"truncated.geojson", `{"type":"FeatureCollection","feat`, false, false},
+		// outputs with manifests were written with checksums, so a missing one means an interrupted write
+		{"interrupted.geojson", `{"type":"FeatureCollection","features":[]}`, true, false},
+	}
+
+	for _, tt := range tests {
+		path := writeTestFile(t, tt.name, tt.data)
+		if tt.manifest {
+			is.NoErr(ioutil.WriteFile(manifestPath(path), []byte("{}"), 0644))
+		}
+
+		err := VerifyOutput(ctx, path)
+		is.Equal(err == nil, tt.ok) // tt.name
+
+		_, err = os.Stat(checksumPath(path))
+		is.Equal(err == nil, tt.ok) // the checksum is written lazily
+	}
+
+	path := writeTestFile(t, "modified.geojson", `{"type":"FeatureCollection","features":[]}`)
+	is.NoErr(VerifyOutput(ctx, path))
+	is.NoErr(ioutil.WriteFile(path, []byte(`{"type":"FeatureCollection","features":[{}]}`), 0644))
+	is.True(VerifyOutput(ctx, path) != nil) // the adopted checksum is verified
+}
+
+func TestInternal(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"vietnam/hanoi.49915.geojson", false},
+		{"vietnam/hanoi.49915.geojson.br", false},
+		{"vietnam/hanoi.49915.geojson.gz", false},
+		{"vietnam/hanoi.49915.geojson.sum", true},
+		{"vietnam/hanoi.49915.geojson.manifest", true},
+		{"vietnam/hanoi.49915.geojson.stale", true},
+		{"vietnam/.hanoi.49915.geojson.123.tmp", true},
+		{"/.cache/index.json", true},
+		{"/vietnam", false},
+		{"/", false},
+		{".checkpoint", true},
+	}
+
+	for _, tt := range tests {
+		is.Equal(Internal(tt.name), tt.want) // tt.name
+	}
+}
@@ -0,0 +1,71 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hiendv/geojson/internal/shared"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+// testLogger records messages of a test.
+type testLogger struct {
+	mu       sync.Mutex
+	messages []string
+	fields   map[string][]interface{}
+}
+
+func (logger *testLogger) record(msg string, fields ...interface{}) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	if logger.fields == nil {
+		logger.fields = map[string][]interface{}{}
+	}
+
+	logger.messages = append(logger.messages, msg)
+	logger.fields[msg] = fields
+}
+
+func (logger *testLogger) Infow(msg string, fields ...interface{})  { logger.record(msg, fields...) }
+func (logger *testLogger) Debugw(msg string, fields ...interface{}) { logger.record(msg, fields...) }
+func (logger *testLogger) Warnw(msg string, fields ...interface{})  { logger.record(msg, fields...) }
+func (logger *testLogger) Errorw(msg string, fields ...interface{}) { logger.record(msg, fields...) }
+func (logger *testLogger) Error(args ...interface{})                { logger.record(fmt.Sprint(args...)) }
+func (logger *testLogger) Clone() shared.Logger                     { return logger }
+func (logger *testLogger) Sync() error                              { return nil }
+
+func testDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	return dir
+}
+
+// newTestContext constructs a context writing outputs of the parent to the directory.
+func newTestContext(logger shared.Logger, out string, separated bool, parent *osm.Relation) context.Context {
+	ctx := context.WithValue(context.Background(), ctxKeyLog, logger)
+	ctx = context.WithValue(ctx, ctxKeyOut, out)
+	ctx = context.WithValue(ctx, ctxKeySeparated, separated)
+	return CtxSetRoot(ctx, parent)
+}
+
+func newTestCollection(features ...*geojson.Feature) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for _, feature := range features {
+		fc.Append(feature)
+	}
+
+	return fc
+}
@@ -0,0 +1,365 @@
+package osm
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hiendv/geojson/pkg/util"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmapi"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/paulmach/osm/osmxml"
+)
+
+// History keeps every version of the objects of a full-history file, e.g. an extract of https://planet.osm.org/pbf/full-history/.
+// Snapshots are built from it instead of the history API.
+type History struct {
+	relations map[int64][]*osm.Relation
+	ways      map[int64][]*osm.Way
+	nodes     map[int64][]*osm.Node
+}
+
+// ReadHistory reads a full-history file into memory: PBF if the extension is .pbf, OSM XML otherwise, e.g. ".osh" or ".osh.gz".
+func ReadHistory(ctx context.Context, path string) (*History, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		r = gz
+	}
+
+	var scanner osm.Scanner
+	if strings.HasSuffix(path, ".pbf") {
+		scanner = osmpbf.New(ctx, r, runtime.GOMAXPROCS(0))
+	} else {
+		scanner = osmxml.New(ctx, r)
+	}
+	defer scanner.Close()
+
+	return scanHistory(scanner)
+}
+
+func scanHistory(scanner osm.Scanner) (*History, error) {
+	h := &History{
+		relations: map[int64][]*osm.Relation{},
+		ways:      map[int64][]*osm.Way{},
+		nodes:     map[int64][]*osm.Node{},
+	}
+
+	for scanner.Scan() {
+		switch o := scanner.Object().(type) {
+		case *osm.Relation:
+			h.relations[int64(o.ID)] = append(h.relations[int64(o.ID)], o)
+		case *osm.Way:
+			h.ways[int64(o.ID)] = append(h.ways[int64(o.ID)], o)
+		case *osm.Node:
+			h.nodes[int64(o.ID)] = append(h.nodes[int64(o.ID)], o)
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// versionAt returns the index of the last version created at or before the time, -1 if there's none.
+// Timestamps must be ordered by their versions.
+func versionAt(timestamps []time.Time, at time.Time) int {
+	index := -1
+	for i, timestamp := range timestamps {
+		if timestamp.After(at) {
+			break
+		}
+
+		index = i
+	}
+
+	return index
+}
+
+// relationAt picks the version of a relation which was current at the time. Nil means it didn't exist.
+func relationAt(relations osm.Relations, at time.Time) *osm.Relation {
+	sort.Slice(relations, func(i, j int) bool {
+		return relations[i].Version < relations[j].Version
+	})
+
+	timestamps := make([]time.Time, 0, len(relations))
+	for _, relation := range relations {
+		timestamps = append(timestamps, relation.Timestamp)
+	}
+
+	i := versionAt(timestamps, at)
+	if i < 0 || !relations[i].Visible {
+		return nil
+	}
+
+	return relations[i]
+}
+
+// wayAt picks the version of a way which was current at the time. Nil means it didn't exist.
+func wayAt(ways osm.Ways, at time.Time) *osm.Way {
+	sort.Slice(ways, func(i, j int) bool {
+		return ways[i].Version < ways[j].Version
+	})
+
+	timestamps := make([]time.Time, 0, len(ways))
+	for _, way := range ways {
+		timestamps = append(timestamps, way.Timestamp)
+	}
+
+	i := versionAt(timestamps, at)
+	if i < 0 || !ways[i].Visible {
+		return nil
+	}
+
+	return ways[i]
+}
+
+// nodeAt picks the version of a node which was current at the time. Nil means it didn't exist.
+func nodeAt(nodes osm.Nodes, at time.Time) *osm.Node {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Version < nodes[j].Version
+	})
+
+	timestamps := make([]time.Time, 0, len(nodes))
+	for _, node := range nodes {
+		timestamps = append(timestamps, node.Timestamp)
+	}
+
+	i := versionAt(timestamps, at)
+	if i < 0 || !nodes[i].Visible {
+		return nil
+	}
+
+	return nodes[i]
+}
+
+// fetchRelationVersion queries a version of a relation.
+func fetchRelationVersion(ctx context.Context, id int64, version int) (*osm.Relation, error) {
+	history, ok := ctxHistory(ctx)
+	if ok {
+		for _, relation := range history.relations[id] {
+			if relation.Version == version {
+				return relation, nil
+			}
+		}
+
+		return nil, fmt.Errorf("relation %d has no version %d in the history", id, version)
+	}
+
+	err := waitUpstream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return osmapi.RelationVersion(ctx, osm.RelationID(id), version)
+}
+
+// fetchRelationAt queries the version of a relation which was current at the time.
+func fetchRelationAt(ctx context.Context, id int64, at time.Time) (*osm.Relation, error) {
+	var relations osm.Relations
+	history, ok := ctxHistory(ctx)
+	if ok {
+		relations = history.relations[id]
+	} else {
+		err := waitUpstream(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		relations, err = osmapi.RelationHistory(ctx, osm.RelationID(id))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	relation := relationAt(relations, at)
+	if relation == nil {
+		return nil, fmt.Errorf("relation %d didn't exist at %s", id, at.Format(time.RFC3339))
+	}
+
+	return relation, nil
+}
+
+// fetchWaysAt queries the versions of ways which were current at the time. Ways which didn't exist are left out.
+// The current versions are queried at once, and only the ways modified since the time are queried through the history API.
+func fetchWaysAt(ctx context.Context, ids []int64, at time.Time) (map[int64]*osm.Way, error) {
+	result := map[int64]*osm.Way{}
+	history, ok := ctxHistory(ctx)
+	if ok {
+		for _, id := range ids {
+			way := wayAt(history.ways[id], at)
+			if way != nil {
+				result[id] = way
+			}
+		}
+
+		return result, nil
+	}
+
+	current, err := fetchWays(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		way, ok := current[id]
+		if ok && !way.Timestamp.After(at) {
+			if way.Visible {
+				result[id] = way
+			}
+
+			continue
+		}
+
+		err := waitUpstream(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ways, err := osmapi.WayHistory(ctx, osm.WayID(id))
+		if err != nil {
+			return nil, err
+		}
+
+		way = wayAt(ways, at)
+		if way != nil {
+			result[id] = way
+		}
+	}
+
+	return result, nil
+}
+
+// fetchNodesAt queries the versions of nodes which were current at the time. Nodes which didn't exist are left out.
+// The current versions are queried at once, and only the nodes modified since the time are queried through the history API.
+func fetchNodesAt(ctx context.Context, ids []int64, at time.Time) (map[int64]*osm.Node, error) {
+	result := map[int64]*osm.Node{}
+	history, ok := ctxHistory(ctx)
+	if ok {
+		for _, id := range ids {
+			node := nodeAt(history.nodes[id], at)
+			if node != nil {
+				result[id] = node
+			}
+		}
+
+		return result, nil
+	}
+
+	current, err := fetchNodes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		node, ok := current[id]
+		if ok && !node.Timestamp.After(at) {
+			if node.Visible {
+				result[id] = node
+			}
+
+			continue
+		}
+
+		err := waitUpstream(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes, err := osmapi.NodeHistory(ctx, osm.NodeID(id))
+		if err != nil {
+			return nil, err
+		}
+
+		node = nodeAt(nodes, at)
+		if node != nil {
+			result[id] = node
+		}
+	}
+
+	return result, nil
+}
+
+// fetchRelationFullAtUncached assembles a relation along with its member ways and their nodes as they were at the time.
+// The history API has no equivalent of the full relation, so members are queried by their current versions and only the modified ones by their histories.
+func fetchRelationFullAtUncached(ctx context.Context, id int64, at time.Time) (*osm.OSM, error) {
+	relation, err := fetchRelationAt(ctx, id, at)
+	if err != nil {
+		return nil, err
+	}
+
+	wayIDs := []int64{}
+	nodes := map[int64]bool{}
+	for _, member := range relation.Members {
+		switch member.Type {
+		case osm.TypeWay:
+			wayIDs = append(wayIDs, member.Ref)
+		case osm.TypeNode:
+			nodes[member.Ref] = true
+		}
+	}
+
+	// ways may be members more than once
+	wayIDs = util.UniqueInt64(wayIDs)
+	ways, err := fetchWaysAt(ctx, wayIDs, at)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &osm.OSM{Relations: osm.Relations{relation}}
+	for _, id := range wayIDs {
+		way, ok := ways[id]
+		if !ok {
+			continue
+		}
+
+		o.Ways = append(o.Ways, way)
+		for _, node := range way.Nodes {
+			nodes[int64(node.ID)] = true
+		}
+	}
+
+	nodeIDs := make([]int64, 0, len(nodes))
+	for id := range nodes {
+		nodeIDs = append(nodeIDs, id)
+	}
+
+	sort.Slice(nodeIDs, func(i, j int) bool {
+		return nodeIDs[i] < nodeIDs[j]
+	})
+
+	found, err := fetchNodesAt(ctx, nodeIDs, at)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range nodeIDs {
+		node, ok := found[id]
+		if ok {
+			o.Nodes = append(o.Nodes, node)
+		}
+	}
+
+	return o, nil
+}
@@ -0,0 +1,170 @@
+package osm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+)
+
+const testHistory = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6">
+  <node id="1" version="1" visible="true" timestamp="2018-01-01T00:00:00Z" lat="21" lon="105"/>
+  <node id="1" version="2" visible="true" timestamp="2020-01-01T00:00:00Z" lat="21.5" lon="105"/>
+  <node id="2" version="1" visible="true" timestamp="2018-01-01T00:00:00Z" lat="21" lon="106"/>
+  <node id="3" version="1" visible="true" timestamp="2018-01-01T00:00:00Z" lat="22" lon="106"/>
+  <node id="4" version="1" visible="true" timestamp="2018-06-01T00:00:00Z" lat="22" lon="107"/>
+  <node id="4" version="2" visible="false" timestamp="2018-07-01T00:00:00Z"/>
+  <way id="10" version="1" visible="true" timestamp="2018-01-01T00:00:00Z">
+    <nd ref="1"/><nd ref="2"/><nd ref="3"/><nd ref="1"/>
+  </way>
+  <way id="11" version="1" visible="true" timestamp="2018-06-01T00:00:00Z">
+    <nd ref="3"/><nd ref="4"/>
+  </way>
+  <way id="11" version="2" visible="false" timestamp="2018-07-01T00:00:00Z"/>
+  <relation id="100" version="1" visible="true" timestamp="2018-01-01T00:00:00Z">
+    <member type="way" ref="10" role="outer"/>
+    <tag k="name" v="Hà Nội"/>
+  </relation>
+  <relation id="100" version="2" visible="true" timestamp="2018-06-01T00:00:00Z">
+    <member type="way" ref="10" role="outer"/>
+    <member type="way" ref="11" role="outer"/>
+    <member type="way" ref="10" role="outer"/>
+    <member type="node" ref="2" role="label"/>
+    <tag k="name" v="Hà Nội"/>
+  </relation>
+</osm>`
+
+func writeTestHistory(t *testing.T, name string) string {
+	if name == "history.osh.gz" {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		_, err := w.Write([]byte(testHistory))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return writeTestFile(t, name, b.String())
+	}
+
+	return writeTestFile(t, name, testHistory)
+}
+
+func TestVersionAt(t *testing.T) {
+	is := is.New(t)
+
+	day := func(d int) time.Time {
+		return time.Date(2019, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	timestamps := []time.Time{day(2), day(4), day(6)}
+
+	tests := []struct {
+		at   time.Time
+		want int
+	}{
+		{day(1), -1},
+		{day(2), 0},
+		{day(3), 0},
+		{day(5), 1},
+		{day(6), 2},
+		{day(9), 2},
+	}
+
+	for _, tt := range tests {
+		is.Equal(versionAt(timestamps, tt.at), tt.want)
+	}
+
+	is.Equal(versionAt(nil, day(1)), -1)
+}
+
+func TestReadHistory(t *testing.T) {
+	is := is.New(t)
+
+	for _, name := range []string{"history.osh", "history.osh.gz"} {
+		history, err := ReadHistory(context.Background(), writeTestHistory(t, name))
+		is.NoErr(err)
+		is.Equal(len(history.relations[100]), 2)
+		is.Equal(len(history.ways[11]), 2)
+		is.Equal(len(history.nodes[1]), 2)
+	}
+
+	_, err := ReadHistory(context.Background(), writeTestFile(t, "broken.osh", "<osm><node"))
+	is.True(err != nil)
+}
+
+func TestFetchRelationFullAtHistory(t *testing.T) {
+	is := is.New(t)
+
+	history, err := ReadHistory(context.Background(), writeTestHistory(t, "history.osh"))
+	is.NoErr(err)
+	ctx := CtxSetHistory(context.Background(), history)
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		version int
+		ways    []osm.WayID
+		nodes   map[osm.NodeID]int // versions
+	}{
+		{
+			"first version",
+			time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC),
+			1,
+			[]osm.WayID{10},
+			map[osm.NodeID]int{1: 1, 2: 1, 3: 1},
+		},
+		{
+			// the way listed twice is fetched once
+			"members added",
+			time.Date(2018, 6, 15, 0, 0, 0, 0, time.UTC),
+			2,
+			[]osm.WayID{10, 11},
+			map[osm.NodeID]int{1: 1, 2: 1, 3: 1, 4: 1},
+		},
+		{
+			// the deleted way and node are left out, the moved node is taken at its new version
+			"members deleted and moved",
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			2,
+			[]osm.WayID{10},
+			map[osm.NodeID]int{1: 2, 2: 1, 3: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		o, err := fetchRelationFullAtUncached(ctx, 100, tt.at)
+		is.NoErr(err)
+		is.Equal(o.Relations[0].Version, tt.version) // tt.name
+
+		ways := []osm.WayID{}
+		for _, way := range o.Ways {
+			ways = append(ways, way.ID)
+		}
+		is.Equal(ways, tt.ways) // tt.name
+
+		nodes := map[osm.NodeID]int{}
+		for _, node := range o.Nodes {
+			nodes[node.ID] = node.Version
+		}
+		is.Equal(nodes, tt.nodes) // tt.name
+	}
+
+	_, err = fetchRelationFullAtUncached(ctx, 100, time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	is.True(err != nil) // didn't exist yet
+
+	relation, err := fetchRelationVersion(ctx, 100, 1)
+	is.NoErr(err)
+	is.Equal(len(relation.Members), 1)
+
+	_, err = fetchRelationVersion(ctx, 100, 3)
+	is.True(err != nil)
+}
@@ -0,0 +1,158 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+const constIDProperty = "property:"
+
+// IDFormat is the strategy of feature IDs.
+// Only GeoJSON is written, TopoJSON and vector tiles are left to converters which keep feature IDs.
+// Numeric and prefixed IDs are integers, which vector tiles require.
+type IDFormat string
+
+const (
+	// IDOSM keeps IDs of osmgeojson, e.g. "relation/962876".
+	IDOSM IDFormat = ""
+	// IDNumeric uses OpenStreetMap IDs, e.g. 962876. IDs of different types may collide.
+	IDNumeric IDFormat = "numeric"
+	// IDPrefixed prefixes OpenStreetMap IDs with a digit of their type: 1 for nodes, 2 for ways and 3 for relations.
+	// E.g. 3962876.
+	IDPrefixed IDFormat = "prefixed"
+)
+
+var idTypePrefixes = map[osm.Type]string{
+	osm.TypeNode:     "1",
+	osm.TypeWay:      "2",
+	osm.TypeRelation: "3",
+}
+
+// ParseIDFormat interprets a string as an IDFormat: osm, numeric, prefixed or property:<name>.
+func ParseIDFormat(str string) (IDFormat, error) {
+	switch format := IDFormat(str); format {
+	case IDOSM, IDNumeric, IDPrefixed:
+		return format, nil
+	case "osm":
+		return IDOSM, nil
+	}
+
+	if strings.HasPrefix(str, constIDProperty) && len(str) > len(constIDProperty) {
+		return IDFormat(str), nil
+	}
+
+	return IDOSM, fmt.Errorf("invalid ID format: %s", str)
+}
+
+// property returns the name of the property promoted to IDs.
+func (format IDFormat) property() (string, bool) {
+	if !strings.HasPrefix(string(format), constIDProperty) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(string(format), constIDProperty), true
+}
+
+// id formats the ID of a feature. The feature is expected to have the ID of osmgeojson.
+func (format IDFormat) id(feature *geojson.Feature) (interface{}, error) {
+	name, ok := format.property()
+	if ok {
+		value, ok := featureProperty(feature, name)
+		if !ok {
+			return nil, fmt.Errorf("missing property: %s", name)
+		}
+
+		return value, nil
+	}
+
+	str, ok := feature.ID.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid ID: %v", feature.ID)
+	}
+
+	parts := strings.Split(str, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid ID: %s", str)
+	}
+
+	switch format {
+	case IDNumeric:
+		return strconv.ParseInt(parts[1], 10, 64)
+	case IDPrefixed:
+		prefix, ok := idTypePrefixes[osm.Type(parts[0])]
+		if !ok {
+			return nil, fmt.Errorf("invalid ID: %s", str)
+		}
+
+		return strconv.ParseInt(prefix+parts[1], 10, 64)
+	}
+
+	return str, nil
+}
+
+// featureProperty looks for a property then a tag of a feature.
+func featureProperty(feature *geojson.Feature, name string) (interface{}, bool) {
+	value, ok := feature.Properties[name]
+	if ok && value != nil && value != "" {
+		return value, true
+	}
+
+	switch tags := feature.Properties[propTags].(type) {
+	case map[string]string:
+		value, ok := tags[name]
+		return value, ok && value != ""
+	case map[string]interface{}:
+		value, ok := tags[name]
+		return value, ok && value != nil && value != ""
+	}
+
+	return nil, false
+}
+
+// identifyFeatureCollection formats IDs of features. Features keep their IDs if they can't be formatted.
+// Formatted IDs must be unique, e.g. a promoted property shared by features fails the collection.
+func identifyFeatureCollection(ctx context.Context, fc *geojson.FeatureCollection) error {
+	format := ctxIDFormat(ctx)
+	if format == IDOSM {
+		return nil
+	}
+
+	log := ctxLog(ctx)
+	for _, feature := range fc.Features {
+		id, err := format.id(feature)
+		if err != nil {
+			log.Warnw("could not format feature ID", "id", feature.ID, "format", format, "error", err)
+			continue
+		}
+
+		feature.ID = id
+	}
+
+	duplicates := duplicateIDs(fc.Features)
+	if len(duplicates) != 0 {
+		return fmt.Errorf("duplicate feature IDs of format %s: %v", format, duplicates)
+	}
+
+	return nil
+}
+
+// duplicateIDs lists IDs shared by features in the order of their first duplicates.
+// IDs are compared by their string forms, as consumers of GeoJSON often do.
+func duplicateIDs(features []*geojson.Feature) []interface{} {
+	seen := map[string]int{}
+	duplicates := []interface{}{}
+	for _, feature := range features {
+		key := fmt.Sprint(feature.ID)
+		seen[key]++
+		if seen[key] == 2 {
+			duplicates = append(duplicates, feature.ID)
+		}
+	}
+
+	return duplicates
+}
@@ -0,0 +1,117 @@
+package osm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func newTestFeature(id string, tags map[string]string) *geojson.Feature {
+	feature := geojson.NewFeature(orb.Point{105.8, 21})
+	feature.ID = id
+	feature.Properties[propTags] = tags
+	return feature
+}
+
+func TestParseIDFormat(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		str    string
+		format IDFormat
+		ok     bool
+	}{
+		{"", IDOSM, true},
+		{"osm", IDOSM, true},
+		{"numeric", IDNumeric, true},
+		{"prefixed", IDPrefixed, true},
+		{"property:ISO3166-2", IDFormat("property:ISO3166-2"), true},
+		{"property:", IDOSM, false},
+		{"uuid", IDOSM, false},
+	}
+
+	for _, test := range tests {
+		format, err := ParseIDFormat(test.str)
+		is.Equal(err == nil, test.ok) // ParseIDFormat(test.str)
+		is.Equal(format, test.format)
+	}
+}
+
+func TestIDFormat(t *testing.T) {
+	is := is.New(t)
+
+	feature := newTestFeature("relation/962876", map[string]string{"ISO3166-2": "VN-HN"})
+	tests := []struct {
+		format IDFormat
+		id     interface{}
+		ok     bool
+	}{
+		{IDOSM, "relation/962876", true},
+		{IDNumeric, int64(962876), true},
+		{IDPrefixed, int64(3962876), true},
+		{IDFormat("property:ISO3166-2"), "VN-HN", true},
+		{IDFormat("property:ref"), nil, false},
+	}
+
+	for _, test := range tests {
+		id, err := test.format.id(feature)
+		is.Equal(err == nil, test.ok) // test.format.id(feature)
+		is.Equal(id, test.id)
+	}
+
+	_, err := IDPrefixed.id(newTestFeature("area/1", nil))
+	is.True(err != nil)
+
+	_, err = IDNumeric.id(newTestFeature("962876", nil))
+	is.True(err != nil)
+}
+
+func TestIdentifyFeatureCollection(t *testing.T) {
+	is := is.New(t)
+
+	newCollection := func() *geojson.FeatureCollection {
+		fc := geojson.NewFeatureCollection()
+		fc.Append(newTestFeature("relation/1", map[string]string{"ISO3166-2": "VN-HN"}))
+		fc.Append(newTestFeature("relation/2", map[string]string{}))
+		return fc
+	}
+
+	fc := newCollection()
+	is.NoErr(identifyFeatureCollection(context.Background(), fc))
+	is.Equal(fc.Features[0].ID, "relation/1")
+
+	fc = newCollection()
+	is.NoErr(identifyFeatureCollection(CtxSetIDFormat(context.Background(), IDPrefixed), fc))
+	is.Equal(fc.Features[0].ID, int64(31))
+	is.Equal(fc.Features[1].ID, int64(32))
+
+	// the feature without the property keeps its ID
+	fc = newCollection()
+	is.NoErr(identifyFeatureCollection(CtxSetIDFormat(context.Background(), IDFormat("property:ISO3166-2")), fc))
+	is.Equal(fc.Features[0].ID, "VN-HN")
+	is.Equal(fc.Features[1].ID, "relation/2")
+
+	fc = newCollection()
+	fc.Append(newTestFeature("relation/3", map[string]string{"ISO3166-2": "VN-HN"}))
+	err := identifyFeatureCollection(CtxSetIDFormat(context.Background(), IDFormat("property:ISO3166-2")), fc)
+	is.True(err != nil)
+}
+
+func TestDuplicateIDs(t *testing.T) {
+	is := is.New(t)
+
+	features := []*geojson.Feature{
+		newTestFeature("a", nil),
+		newTestFeature("b", nil),
+		newTestFeature("a", nil),
+		newTestFeature("a", nil),
+	}
+	features[1].ID = int64(1)
+	features = append(features, newTestFeature("1", nil))
+
+	is.Equal(duplicateIDs(features), []interface{}{"a", "1"})
+	is.Equal(duplicateIDs(nil), []interface{}{})
+}
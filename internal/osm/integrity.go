@@ -0,0 +1,118 @@
+package osm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+// Completeness classifies the geometry of a sub-area.
+type Completeness string
+
+const (
+	// CompletenessComplete means every member way is part of a closed ring.
+	CompletenessComplete Completeness = "complete"
+	// CompletenessPartial means some member ways are missing or do not form closed rings.
+	CompletenessPartial Completeness = "partial"
+	// CompletenessEmpty means no geometry could be built.
+	CompletenessEmpty Completeness = "empty"
+)
+
+// Link returns the URL of an OpenStreetMap object.
+func Link(t osm.Type, id int64) string {
+	return fmt.Sprintf("https://www.openstreetmap.org/%s/%d", t, id)
+}
+
+// checkIntegrity classifies the converted geometry of a relation and looks for the offending member ways.
+// A way is offending when it is missing, has missing nodes or has an endpoint which no other way continues.
+func checkIntegrity(o *osm.OSM, id int64, features []*geojson.Feature) (Completeness, []int64) {
+	var relation *osm.Relation
+	for _, r := range o.Relations {
+		if int64(r.ID) == id {
+			relation = r
+			break
+		}
+	}
+
+	if relation == nil {
+		return CompletenessEmpty, nil
+	}
+
+	ways := map[osm.WayID]*osm.Way{}
+	for _, w := range o.Ways {
+		ways[w.ID] = w
+	}
+
+	nodes := map[osm.NodeID]bool{}
+	for _, n := range o.Nodes {
+		nodes[n.ID] = true
+	}
+
+	offending := map[int64]bool{}
+	roles := map[string][]*osm.Way{}
+	for _, member := range relation.Members {
+		if member.Type != osm.TypeWay {
+			continue
+		}
+
+		if member.Role != "outer" && member.Role != "inner" {
+			continue
+		}
+
+		way, ok := ways[osm.WayID(member.Ref)]
+		if !ok || len(way.Nodes) < 2 {
+			offending[member.Ref] = true
+			continue
+		}
+
+		for _, node := range way.Nodes {
+			if node.Lat == 0 && node.Lon == 0 && !nodes[node.ID] {
+				offending[member.Ref] = true
+				break
+			}
+		}
+
+		roles[member.Role] = append(roles[member.Role], way)
+	}
+
+	// rings are closed if and only if every endpoint is shared by an even number of ways
+	for _, members := range roles {
+		degrees := map[osm.NodeID]int{}
+		for _, way := range members {
+			degrees[way.Nodes[0].ID]++
+			degrees[way.Nodes[len(way.Nodes)-1].ID]++
+		}
+
+		for _, way := range members {
+			if degrees[way.Nodes[0].ID]%2 != 0 || degrees[way.Nodes[len(way.Nodes)-1].ID]%2 != 0 {
+				offending[int64(way.ID)] = true
+			}
+		}
+	}
+
+	result := make([]int64, 0, len(offending))
+	for ref := range offending {
+		result = append(result, ref)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	if len(features) == 0 || features[0].Geometry == nil {
+		return CompletenessEmpty, result
+	}
+
+	if len(result) != 0 {
+		return CompletenessPartial, result
+	}
+
+	tainted, _ := features[0].Properties["tainted"].(bool)
+	if tainted {
+		return CompletenessPartial, result
+	}
+
+	return CompletenessComplete, result
+}
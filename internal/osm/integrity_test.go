@@ -0,0 +1,100 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+func newTestWay(id osm.WayID, nodes ...osm.NodeID) *osm.Way {
+	way := &osm.Way{ID: id}
+	for _, node := range nodes {
+		way.Nodes = append(way.Nodes, osm.WayNode{ID: node, Lat: 21, Lon: 105})
+	}
+
+	return way
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	is := is.New(t)
+
+	relation := &osm.Relation{ID: 5, Members: osm.Members{
+		{Type: osm.TypeWay, Ref: 1, Role: "outer"},
+		{Type: osm.TypeWay, Ref: 2, Role: "outer"},
+		{Type: osm.TypeWay, Ref: 3, Role: "inner"},
+		{Type: osm.TypeNode, Ref: 9, Role: "admin_centre"},
+	}}
+	hole := newTestWay(3, 5, 6, 7, 5)
+	features := []*geojson.Feature{newTestFeature("relation/5", nil)}
+
+	tests := []struct {
+		name      string
+		o         *osm.OSM
+		features  []*geojson.Feature
+		status    Completeness
+		offending []int64
+	}{
+		{
+			"closed rings",
+			&osm.OSM{Relations: osm.Relations{relation}, Ways: osm.Ways{newTestWay(1, 1, 2, 3), newTestWay(2, 3, 4, 1), hole}},
+			features,
+			CompletenessComplete,
+			[]int64{},
+		},
+		{
+			"missing way",
+			&osm.OSM{Relations: osm.Relations{relation}, Ways: osm.Ways{newTestWay(1, 1, 2, 3), hole}},
+			features,
+			CompletenessPartial,
+			[]int64{1, 2},
+		},
+		{
+			"gap between ways",
+			&osm.OSM{Relations: osm.Relations{relation}, Ways: osm.Ways{newTestWay(1, 1, 2, 3), newTestWay(2, 4, 1), hole}},
+			features,
+			CompletenessPartial,
+			[]int64{1, 2},
+		},
+		{
+			"missing node",
+			&osm.OSM{Relations: osm.Relations{relation}, Ways: osm.Ways{newTestWay(1, 1, 2, 3), newTestWay(2, 3, 4, 1), {ID: 3, Nodes: osm.WayNodes{{ID: 5}, {ID: 6, Lat: 1, Lon: 1}, {ID: 5}}}}},
+			features,
+			CompletenessPartial,
+			[]int64{3},
+		},
+		{
+			"no geometry",
+			&osm.OSM{Relations: osm.Relations{relation}, Ways: osm.Ways{newTestWay(1, 1, 2, 3), newTestWay(2, 3, 4, 1), hole}},
+			[]*geojson.Feature{},
+			CompletenessEmpty,
+			[]int64{},
+		},
+		{
+			"missing relation",
+			&osm.OSM{},
+			features,
+			CompletenessEmpty,
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		status, offending := checkIntegrity(test.o, 5, test.features)
+		is.Equal(status, test.status)       // test.name
+		is.Equal(offending, test.offending) // test.name
+	}
+
+	// osmgeojson taints geometries built from incomplete data
+	tainted := newTestFeature("relation/5", nil)
+	tainted.Properties["tainted"] = true
+	status, _ := checkIntegrity(tests[0].o, 5, []*geojson.Feature{tainted})
+	is.Equal(status, CompletenessPartial)
+}
+
+func TestLink(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(Link(osm.TypeWay, 42), "https://www.openstreetmap.org/way/42")
+}
@@ -0,0 +1,295 @@
+package osm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+const constManifestExt = ".manifest"
+
+// Manifest is the sidecar of an output which records the versions of the objects it was built from along with the options it was built with.
+type Manifest struct {
+	Parent   *ObjectVersion    `json:"parent,omitempty"` // merged outputs only
+	SubAreas []SubAreaManifest `json:"sub_areas"`
+	Options  string            `json:"options"` // SHA-256 of the options
+}
+
+// ObjectVersion is the version of an OpenStreetMap object.
+type ObjectVersion struct {
+	ID      int64 `json:"id"`
+	Version int   `json:"version"`
+}
+
+// SubAreaManifest records the versions of a sub-area, its member ways and their nodes.
+// Moving a node changes neither the version of its way nor the version of the relation, so nodes are recorded as well.
+type SubAreaManifest struct {
+	ID       int64         `json:"id"`
+	Version  int           `json:"version"`
+	Status   Completeness  `json:"status"`
+	Ways     map[int64]int `json:"ways"`
+	Nodes    map[int64]int `json:"nodes"`
+	Features []int         `json:"features,omitempty"` // positions in the merged output
+}
+
+// outputOptions are the options which change the content of outputs.
+type outputOptions struct {
+	Normalize  bool           `json:"normalize"`
+	Rewind     bool           `json:"rewind"`
+	Precision  int            `json:"precision"`
+	Validate   ValidationMode `json:"validate"`
+	Centroid   bool           `json:"centroid"`
+	LabelPoint bool           `json:"label_point"`
+	Measure    MeasureUnit    `json:"measure"`
+	Tags       string         `json:"tags"`
+	Normalized string         `json:"normalized"`
+	Normalizer string         `json:"normalizer"`
+	Lang       []string       `json:"lang"`
+	Mapping    *Mapping       `json:"mapping"`
+	Meta       bool           `json:"meta"`
+	ID         IDFormat       `json:"id"`
+	Sort       string         `json:"sort"`
+}
+
+// optionsHash hashes the options which change the content of outputs, so outputs built with other options aren't reused.
+func optionsHash(ctx context.Context) string {
+	precision, ok := ctxPrecision(ctx)
+	if !ok {
+		precision = -1
+	}
+
+	mapping, _ := ctxMapping(ctx)
+	options := outputOptions{
+		Normalize:  ctxShouldNormalize(ctx),
+		Rewind:     ctxShouldRewind(ctx),
+		Precision:  precision,
+		Validate:   ctxValidation(ctx),
+		Centroid:   ctxShouldCentroid(ctx),
+		LabelPoint: ctxShouldLabelPoint(ctx),
+		Measure:    ctxMeasure(ctx),
+		Tags:       fmt.Sprint(ctxTags(ctx)),
+		Normalized: fmt.Sprint(ctxNormalizedTags(ctx)),
+		Normalizer: ctxNormalizerForm(ctx),
+		Lang:       ctxLanguages(ctx),
+		Mapping:    mapping,
+		Meta:       ctxShouldMeta(ctx),
+		ID:         ctxIDFormat(ctx),
+		Sort:       ctxSort(ctx),
+	}
+
+	// options are plain values so they always marshal
+	data, _ := json.Marshal(options)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// previousOutput is an intact output of a previous run along with its manifest.
+type previousOutput struct {
+	manifest Manifest
+	subAreas map[int64]SubAreaManifest
+	fc       *geojson.FeatureCollection // merged outputs only
+}
+
+func manifestPath(path string) string {
+	return path + constManifestExt
+}
+
+// readPreviousOutput reads the output of a relation if it's intact and has a manifest.
+func readPreviousOutput(ctx context.Context, id int64) (*previousOutput, bool) {
+	path, ok := globFilePath(ctx, id)
+	if !ok || verifyChecksum(path) != nil {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(manifestPath(path))
+	if err != nil {
+		return nil, false
+	}
+
+	previous := &previousOutput{subAreas: map[int64]SubAreaManifest{}}
+	err = json.Unmarshal(data, &previous.manifest)
+	if err != nil {
+		return nil, false
+	}
+
+	// outputs built with other options are regenerated, including the ones without options
+	if previous.manifest.Options != optionsHash(ctx) {
+		return nil, false
+	}
+
+	for _, subArea := range previous.manifest.SubAreas {
+		previous.subAreas[subArea.ID] = subArea
+	}
+
+	if previous.manifest.Parent == nil {
+		return previous, true
+	}
+
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	previous.fc, err = geojson.UnmarshalFeatureCollection(data)
+	if err != nil {
+		return nil, false
+	}
+
+	return previous, true
+}
+
+// reuseSubArea returns a sub-area of the previous output if neither it, its member ways nor their nodes have changed since.
+// Otherwise the current full relation is returned if it was fetched to compare the versions, so it's not fetched again.
+func reuseSubArea(ctx context.Context, id int64) (subArea, *osm.OSM, bool) {
+	previous, ok := ctxPreviousOutput(ctx)
+	if !ctxShouldCombine(ctx) {
+		previous, ok = readPreviousOutput(ctx, id)
+	}
+
+	if !ok {
+		return subArea{}, nil, false
+	}
+
+	manifest, ok := previous.subAreas[id]
+	if !ok {
+		return subArea{}, nil, false
+	}
+
+	current, ok := upToDate(ctx, manifest)
+	if !ok {
+		// the cached response may predate the change, e.g. a moved node
+		ctxResponseCache(ctx).remove(osm.TypeRelation, id)
+		if current != nil {
+			ctxResponseCache(ctx).store(ctx, id, current)
+		}
+
+		return subArea{}, current, false
+	}
+
+	result := subArea{id: id, version: manifest.Version, status: manifest.Status, wayVersions: manifest.Ways, nodeVersions: manifest.Nodes, unchanged: true}
+	if previous.fc == nil {
+		return result, nil, true
+	}
+
+	result.fc = geojson.NewFeatureCollection()
+	for _, i := range manifest.Features {
+		if i < 0 || i >= len(previous.fc.Features) {
+			return subArea{}, current, false
+		}
+
+		result.fc.Append(previous.fc.Features[i])
+	}
+
+	return result, nil, true
+}
+
+// upToDate compares the versions of a sub-area, its member ways and their nodes with the current ones, returning the current full relation as well.
+// The full relation is fetched at once, bypassing the cache, so the number of requests doesn't grow with the sub-area.
+func upToDate(ctx context.Context, manifest SubAreaManifest) (*osm.OSM, bool) {
+	// manifests written before nodes were recorded can't tell moved nodes
+	if manifest.Nodes == nil {
+		return nil, false
+	}
+
+	o, err := fetchRelationFullUncached(ctx, manifest.ID)
+	if err != nil {
+		return nil, false
+	}
+
+	return o, currentVersions(manifest, o)
+}
+
+// currentVersions determines if the versions of a sub-area, its member ways and their nodes match the ones of the full relation.
+func currentVersions(manifest SubAreaManifest, o *osm.OSM) bool {
+	version := 0
+	for _, relation := range o.Relations {
+		if int64(relation.ID) == manifest.ID {
+			version = relation.Version
+		}
+	}
+
+	if version != manifest.Version {
+		return false
+	}
+
+	ways := map[int64]int{}
+	for _, way := range o.Ways {
+		ways[int64(way.ID)] = way.Version
+	}
+
+	nodes := map[int64]int{}
+	for _, node := range o.Nodes {
+		nodes[int64(node.ID)] = node.Version
+	}
+
+	// members which are gone change the version of the relation or their ways as well
+	return sameVersions(manifest.Ways, ways) && sameVersions(manifest.Nodes, nodes)
+}
+
+// sameVersions determines if the current versions of objects match the cached ones. Missing objects are deleted, so they don't match.
+func sameVersions(cached map[int64]int, current map[int64]int) bool {
+	for id, version := range cached {
+		if current[id] != version {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newSubAreaManifest records the versions of a sub-area.
+func newSubAreaManifest(result subArea) SubAreaManifest {
+	return SubAreaManifest{ID: result.id, Version: result.version, Status: result.status, Ways: result.wayVersions, Nodes: result.nodeVersions}
+}
+
+// newMergedManifest records the versions of a parent and its sub-areas along with the positions of their features.
+func newMergedManifest(parent ObjectVersion, handled []subArea, features []orderedFeature) *Manifest {
+	positions := map[int64][]int{}
+	for i, feature := range features {
+		positions[feature.id] = append(positions[feature.id], i)
+	}
+
+	manifest := &Manifest{Parent: &parent, SubAreas: []SubAreaManifest{}}
+	for _, result := range handled {
+		if result.err != nil {
+			continue
+		}
+
+		subArea := newSubAreaManifest(result)
+		subArea.Features = positions[result.id]
+		manifest.SubAreas = append(manifest.SubAreas, subArea)
+	}
+
+	sort.Slice(manifest.SubAreas, func(i, j int) bool {
+		return manifest.SubAreas[i].ID < manifest.SubAreas[j].ID
+	})
+
+	return manifest
+}
+
+// unchangedOutput determines if a merged output would be rewritten as it is.
+func unchangedOutput(ctx context.Context, parent ObjectVersion, handled []subArea) bool {
+	previous, ok := ctxPreviousOutput(ctx)
+	if !ok || previous.manifest.Parent == nil || *previous.manifest.Parent != parent {
+		return false
+	}
+
+	if len(handled) != len(previous.subAreas) {
+		return false
+	}
+
+	for _, result := range handled {
+		if !result.unchanged {
+			return false
+		}
+	}
+
+	return true
+}
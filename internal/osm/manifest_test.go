@@ -0,0 +1,129 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+)
+
+func TestOptionsHash(t *testing.T) {
+	is := is.New(t)
+
+	ctx := newTestContext(&testLogger{}, testDir(t), true, &osm.Relation{ID: 1})
+	ascii, err := CtxSetNormalizer(ctx, "ascii")
+	is.NoErr(err)
+	strip, err := CtxSetNormalizer(ctx, "")
+	is.NoErr(err)
+
+	hash := optionsHash(ctx)
+	is.Equal(optionsHash(ctx), hash)                                    // stable
+	is.Equal(optionsHash(strip), hash)                                  // the default normalizer
+	is.Equal(optionsHash(CtxSetCompression(ctx, []string{"br"})), hash) // sidecars don't change the content
+
+	changed := map[string]string{ // by option
+		"precision":  optionsHash(CtxSetPrecision(ctx, 6)),
+		"lang":       optionsHash(CtxSetLanguages(ctx, []string{"vi"})),
+		"mapping":    optionsHash(CtxSetMapping(ctx, &Mapping{KeepTags: true})),
+		"id":         optionsHash(CtxSetIDFormat(ctx, IDNumeric)),
+		"validate":   optionsHash(CtxSetValidation(ctx, ValidationFix)),
+		"measure":    optionsHash(CtxSetMeasure(ctx, MeasureKilometer)),
+		"tags":       optionsHash(CtxSetTags(ctx, TagFilter{all: true})),
+		"normalizer": optionsHash(ascii),
+	}
+	for _, h := range changed {
+		is.True(h != hash) // every option changes the content
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	is := is.New(t)
+
+	dir := testDir(t)
+	ctx := CtxSetPrecision(newTestContext(&testLogger{}, dir, true, &osm.Relation{ID: 1}), 6)
+	result := subArea{
+		id:           11,
+		name:         "Hà Nội",
+		json:         []byte(`{"type":"FeatureCollection","features":[]}`),
+		version:      7,
+		status:       CompletenessComplete,
+		wayVersions:  map[int64]int{101: 2},
+		nodeVersions: map[int64]int{1001: 4, 1002: 1},
+	}
+	is.NoErr(reportResult(ctx, result))
+
+	previous, ok := readPreviousOutput(ctx, 11)
+	is.True(ok)
+	is.Equal(previous.manifest.Options, optionsHash(ctx))
+	is.Equal(previous.subAreas[11], SubAreaManifest{
+		ID:      11,
+		Version: 7,
+		Status:  CompletenessComplete,
+		Ways:    map[int64]int{101: 2},
+		Nodes:   map[int64]int{1001: 4, 1002: 1},
+	})
+
+	_, ok = readPreviousOutput(CtxSetPrecision(ctx, 5), 11)
+	is.True(!ok) // built with other options
+
+	// manifests without nodes can't be up-to-date, no request is made
+	current, ok := upToDate(ctx, SubAreaManifest{ID: 11, Version: 7, Ways: map[int64]int{101: 2}})
+	is.True(!ok)
+	is.True(current == nil)
+}
+
+func TestSameVersions(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		name    string
+		current map[int64]int
+		want    bool
+	}{
+		{"unchanged", map[int64]int{1: 3, 2: 7}, true},
+		{"added", map[int64]int{1: 3, 2: 7, 3: 1}, true},
+		{"modified", map[int64]int{1: 4, 2: 7}, false},
+		{"deleted", map[int64]int{1: 3}, false},
+	}
+
+	for _, tt := range tests {
+		is.Equal(sameVersions(map[int64]int{1: 3, 2: 7}, tt.current), tt.want) // tt.name
+	}
+}
+
+func TestCurrentVersions(t *testing.T) {
+	is := is.New(t)
+	manifest := SubAreaManifest{ID: 11, Version: 7, Ways: map[int64]int{101: 2}, Nodes: map[int64]int{1001: 4, 1002: 1}}
+
+	tests := []struct {
+		name string
+		o    *osm.OSM
+		want bool
+	}{
+		{"unchanged", &osm.OSM{
+			Relations: osm.Relations{{ID: 11, Version: 7}},
+			Ways:      osm.Ways{{ID: 101, Version: 2}},
+			Nodes:     osm.Nodes{{ID: 1001, Version: 4}, {ID: 1002, Version: 1}},
+		}, true},
+		{"relation modified", &osm.OSM{
+			Relations: osm.Relations{{ID: 11, Version: 8}},
+			Ways:      osm.Ways{{ID: 101, Version: 2}},
+			Nodes:     osm.Nodes{{ID: 1001, Version: 4}, {ID: 1002, Version: 1}},
+		}, false},
+		{"way modified", &osm.OSM{
+			Relations: osm.Relations{{ID: 11, Version: 7}},
+			Ways:      osm.Ways{{ID: 101, Version: 3}},
+			Nodes:     osm.Nodes{{ID: 1001, Version: 4}, {ID: 1002, Version: 1}},
+		}, false},
+		{"node moved", &osm.OSM{
+			Relations: osm.Relations{{ID: 11, Version: 7}},
+			Ways:      osm.Ways{{ID: 101, Version: 2}},
+			Nodes:     osm.Nodes{{ID: 1001, Version: 5}, {ID: 1002, Version: 1}},
+		}, false},
+		{"relation missing", &osm.OSM{}, false},
+	}
+
+	for _, tt := range tests {
+		is.Equal(currentVersions(manifest, tt.o), tt.want) // tt.name
+	}
+}
@@ -0,0 +1,224 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+	"gopkg.in/yaml.v2"
+)
+
+const propTags = "tags"
+
+// mappingPlaceholder matches a tag placeholder of a template, e.g. "{name:en}".
+var mappingPlaceholder = regexp.MustCompile(`{([^{}]+)}`)
+
+// Mapping reshapes OpenStreetMap tags into feature properties.
+type Mapping struct {
+	// Properties are built in order from tags. They replace the "tags" property unless KeepTags is set.
+	Properties []PropertyMapping `json:"properties" yaml:"properties"`
+	// KeepTags keeps the "tags" property along with the mapped properties.
+	KeepTags bool `json:"keep_tags" yaml:"keep_tags"`
+	// Drop lists patterns of tags removed from the kept "tags" property, e.g. "name:*".
+	Drop []string `json:"drop" yaml:"drop"`
+	// Types coerces mapped properties and kept tags by their names, e.g. "admin_level: integer".
+	Types map[string]PropertyType `json:"types" yaml:"types"`
+}
+
+// PropertyMapping builds a property from tags.
+// Sources are tried in order: From, Template then Default. Properties without value are omitted.
+type PropertyMapping struct {
+	Name string `json:"name" yaml:"name"`
+	// From lists tags by the order of preference, the first present one is taken.
+	From []string `json:"from" yaml:"from"`
+	// Template combines tags, e.g. "{name} ({ref})". It's skipped if any of the tags is missing.
+	Template string `json:"template" yaml:"template"`
+	Default  string `json:"default" yaml:"default"`
+}
+
+// ReadMapping reads a mapping file, which is YAML if the extension is .yaml or .yml and JSON otherwise.
+func ReadMapping(file string) (*Mapping, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := &Mapping{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, mapping)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(mapping)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping %s: %w", file, err)
+	}
+
+	err = mapping.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping %s: %w", file, err)
+	}
+
+	return mapping, nil
+}
+
+func (mapping *Mapping) validate() error {
+	names := map[string]bool{}
+	for _, property := range mapping.Properties {
+		if property.Name == "" {
+			return errors.New("property without name")
+		}
+
+		if property.Name == propTags && mapping.KeepTags {
+			return errors.New("property \"tags\" conflicts with keep_tags")
+		}
+
+		if names[property.Name] {
+			return fmt.Errorf("duplicate property: %s", property.Name)
+		}
+		names[property.Name] = true
+
+		if len(property.From) == 0 && property.Template == "" && property.Default == "" {
+			return fmt.Errorf("property without source: %s", property.Name)
+		}
+
+		if strings.Count(property.Template, "{") != len(mappingPlaceholder.FindAllString(property.Template, -1)) {
+			return fmt.Errorf("invalid template of %s: %s", property.Name, property.Template)
+		}
+	}
+
+	for _, pattern := range mapping.Drop {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid tag pattern: %s", pattern)
+		}
+	}
+
+	for _, kind := range mapping.Types {
+		_, err := ParsePropertyType(string(kind))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keys returns the tags the mapping refers to.
+func (mapping *Mapping) keys() []string {
+	keys := []string{}
+	for _, property := range mapping.Properties {
+		keys = append(keys, property.From...)
+		for _, match := range mappingPlaceholder.FindAllStringSubmatch(property.Template, -1) {
+			keys = append(keys, match[1])
+		}
+	}
+
+	// typed tags are selected as well
+	for key := range mapping.Types {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// properties builds the mapped properties from tags.
+func (mapping *Mapping) properties(tags map[string]string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, property := range mapping.Properties {
+		value := property.value(tags)
+		if value == "" {
+			continue
+		}
+
+		properties[property.Name] = value
+	}
+
+	if !mapping.KeepTags && len(mapping.Properties) != 0 {
+		return properties
+	}
+
+	kept := map[string]string{}
+	for key, value := range tags {
+		if mapping.dropped(key) {
+			continue
+		}
+
+		kept[key] = value
+	}
+
+	properties[propTags] = kept
+	return properties
+}
+
+func (mapping *Mapping) dropped(key string) bool {
+	for _, pattern := range mapping.Drop {
+		matched, _ := path.Match(pattern, key)
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (property PropertyMapping) value(tags map[string]string) string {
+	for _, key := range property.From {
+		if tags[key] != "" {
+			return tags[key]
+		}
+	}
+
+	if property.Template != "" {
+		missing := false
+		value := mappingPlaceholder.ReplaceAllStringFunc(property.Template, func(placeholder string) string {
+			v := tags[placeholder[1:len(placeholder)-1]]
+			if v == "" {
+				missing = true
+			}
+
+			return v
+		})
+
+		if !missing {
+			return value
+		}
+	}
+
+	return property.Default
+}
+
+// mapFeatureCollection replaces the "tags" property of features with the mapped and coerced properties.
+// Values which could not be coerced are reported per feature.
+func mapFeatureCollection(ctx context.Context, fc *geojson.FeatureCollection) {
+	mapping, ok := ctxMapping(ctx)
+	if !ok {
+		return
+	}
+
+	log := ctxLog(ctx)
+	for _, feature := range fc.Features {
+		tags, _ := feature.Properties[propTags].(map[string]string)
+		properties := mapping.properties(tags)
+		issues := coerceProperties(properties, mapping.Types)
+		if len(issues) != 0 {
+			log.Warnw("invalid property values", "id", feature.ID, "issues", issues)
+		}
+
+		delete(feature.Properties, propTags)
+		for key, value := range properties {
+			feature.Properties[key] = value
+		}
+	}
+}
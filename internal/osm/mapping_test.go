@@ -0,0 +1,118 @@
+package osm
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func writeTestFile(t *testing.T, name string, data string) string {
+	path := filepath.Join(testDir(t), name)
+	err := ioutil.WriteFile(path, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestReadMapping(t *testing.T) {
+	is := is.New(t)
+
+	mapping, err := ReadMapping(writeTestFile(t, "mapping.yaml", `
+properties:
+  - name: name
+    from: [name:en, name]
+  - name: label
+    template: "{name} ({ref})"
+    default: unknown
+keep_tags: true
+drop: ["name:*"]
+types:
+  admin_level: integer
+`))
+	is.NoErr(err)
+	is.Equal(len(mapping.Properties), 2)
+	is.Equal(mapping.Properties[0].From, []string{"name:en", "name"})
+	is.True(mapping.KeepTags)
+	is.Equal(mapping.Types, map[string]PropertyType{"admin_level": TypeInteger})
+
+	mapping, err = ReadMapping(writeTestFile(t, "mapping.json", `{"properties": [{"name": "iso", "from": ["ISO3166-2"]}]}`))
+	is.NoErr(err)
+	is.Equal(mapping.Properties[0].Name, "iso")
+
+	invalid := []string{
+		`{"properties": [{"from": ["name"]}]}`,
+		`{"properties": [{"name": "a", "from": ["name"]}, {"name": "a", "from": ["ref"]}]}`,
+		`{"properties": [{"name": "a"}]}`,
+		`{"properties": [{"name": "a", "template": "{name"}]}`,
+		`{"properties": [{"name": "tags", "from": ["name"]}], "keep_tags": true}`,
+		`{"drop": ["[name"]}`,
+		`{"types": {"admin_level": "float"}}`,
+		`{"unknown": true}`,
+	}
+
+	for _, data := range invalid {
+		_, err = ReadMapping(writeTestFile(t, "mapping.json", data))
+		is.True(err != nil) // invalid mapping
+	}
+
+	_, err = ReadMapping(filepath.Join(os.TempDir(), "missing-mapping.json"))
+	is.True(err != nil)
+}
+
+func TestMappingProperties(t *testing.T) {
+	is := is.New(t)
+
+	mapping := &Mapping{
+		Properties: []PropertyMapping{
+			{Name: "name", From: []string{"name:en", "name"}},
+			{Name: "label", Template: "{name} ({ref})", Default: "unknown"},
+			{Name: "iso", From: []string{"ISO3166-2"}},
+		},
+	}
+
+	tags := map[string]string{"name": "Hà Nội", "name:vi": "Hà Nội", "admin_level": "4"}
+	is.Equal(mapping.properties(tags), map[string]interface{}{"name": "Hà Nội", "label": "unknown"})
+
+	tags["ref"] = "HN"
+	tags["name:en"] = "Hanoi"
+	is.Equal(mapping.properties(tags), map[string]interface{}{"name": "Hanoi", "label": "Hà Nội (HN)"})
+
+	mapping.KeepTags = true
+	mapping.Drop = []string{"name:*"}
+	is.Equal(mapping.properties(tags), map[string]interface{}{
+		"name":   "Hanoi",
+		"label":  "Hà Nội (HN)",
+		propTags: map[string]string{"name": "Hà Nội", "admin_level": "4", "ref": "HN"},
+	})
+
+	is.Equal(mapping.keys(), []string{"name:en", "name", "name", "ref", "ISO3166-2"})
+}
+
+func TestMapFeatureCollection(t *testing.T) {
+	is := is.New(t)
+
+	fc := geojson.NewFeatureCollection()
+	feature := geojson.NewFeature(orb.Point{105.8, 21})
+	feature.Properties[propTags] = map[string]string{"name": "Hà Nội", "admin_level": "4"}
+	feature.Properties["id"] = "relation/1903516"
+	fc.Append(feature)
+
+	// nothing is mapped without a mapping
+	mapFeatureCollection(context.Background(), fc)
+	is.Equal(feature.Properties[propTags], map[string]string{"name": "Hà Nội", "admin_level": "4"})
+
+	ctx := CtxSetMapping(context.Background(), &Mapping{
+		Properties: []PropertyMapping{{Name: "name", From: []string{"name"}}, {Name: "level", From: []string{"admin_level"}}},
+		Types:      map[string]PropertyType{"level": TypeInteger},
+	})
+	mapFeatureCollection(ctx, fc)
+	is.Equal(map[string]interface{}(feature.Properties), map[string]interface{}{"id": "relation/1903516", "name": "Hà Nội", "level": int64(4)})
+}
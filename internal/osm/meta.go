@@ -0,0 +1,90 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+// Meta is the metadata of an OpenStreetMap element, following the "meta" property of osmgeojson.
+// Timestamp is a pointer since omitempty never omits a struct, which would encode a missing timestamp as "0001-01-01T00:00:00Z".
+type Meta struct {
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Version   int        `json:"version,omitempty"`
+	Changeset int64      `json:"changeset,omitempty"`
+	User      string     `json:"user,omitempty"`
+	UserID    int64      `json:"uid,omitempty"`
+}
+
+// Parent is the foreign member describing the parent relation of a feature collection.
+type Parent struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	Meta *Meta  `json:"meta,omitempty"`
+}
+
+// featureCollection is a GeoJSON feature collection with foreign members.
+// Fields of geojson.FeatureCollection are repeated because its MarshalJSON would shadow the others.
+type featureCollection struct {
+	Type     string             `json:"type"`
+	BBox     geojson.BBox       `json:"bbox,omitempty"`
+	Features []*geojson.Feature `json:"features"`
+	Parent   *Parent            `json:"parent,omitempty"`
+	Snapshot *Snapshot          `json:"snapshot,omitempty"`
+}
+
+// Snapshot is the foreign member describing the time of a historical output.
+type Snapshot struct {
+	At time.Time `json:"at"`
+}
+
+func newMeta(relation *osm.Relation) *Meta {
+	meta := &Meta{
+		Version:   relation.Version,
+		Changeset: int64(relation.ChangesetID),
+		User:      relation.User,
+		UserID:    int64(relation.UserID),
+	}
+
+	if !relation.Timestamp.IsZero() {
+		timestamp := relation.Timestamp.UTC()
+		meta.Timestamp = &timestamp
+	}
+
+	return meta
+}
+
+// marshalFeatureCollection encodes a feature collection along with its parent if the metadata is requested.
+// Snapshots get their time as well.
+func marshalFeatureCollection(ctx context.Context, fc *geojson.FeatureCollection) ([]byte, error) {
+	root, ok := ctxRoot(ctx)
+	at, isSnapshot := ctxSnapshot(ctx)
+	if !ctxShouldMeta(ctx) || !ok || root == nil {
+		return json.Marshal(fc)
+	}
+
+	features := fc.Features
+	if features == nil {
+		features = []*geojson.Feature{}
+	}
+
+	collection := featureCollection{
+		Type:     "FeatureCollection",
+		BBox:     fc.BBox,
+		Features: features,
+		Parent: &Parent{
+			ID:   int64(root.ID),
+			Type: string(osm.TypeRelation),
+			Meta: newMeta(root),
+		},
+	}
+
+	if isSnapshot {
+		collection.Snapshot = &Snapshot{At: at.UTC()}
+	}
+
+	return json.Marshal(collection)
+}
@@ -0,0 +1,44 @@
+package osm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+)
+
+func TestMarshalFeatureCollectionMeta(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		name     string
+		relation *osm.Relation
+		want     string
+	}{
+		{"full", &osm.Relation{
+			ID:          1903516,
+			Version:     12,
+			ChangesetID: 345,
+			Timestamp:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			User:        "mapper",
+			UserID:      67,
+		}, `{"timestamp":"2020-01-01T00:00:00Z","version":12,"changeset":345,"user":"mapper","uid":67}`},
+		{"zero", &osm.Relation{ID: 1903516}, `{}`},
+	}
+
+	for _, tt := range tests {
+		ctx := CtxSetMeta(newTestContext(&testLogger{}, testDir(t), true, tt.relation), true)
+		data, err := marshalFeatureCollection(ctx, newTestCollection())
+		is.NoErr(err)
+
+		collection := struct {
+			Parent struct {
+				Meta json.RawMessage `json:"meta"`
+			} `json:"parent"`
+		}{}
+		is.NoErr(json.Unmarshal(data, &collection))
+		is.Equal(string(collection.Parent.Meta), tt.want) // tt.name
+	}
+}
@@ -0,0 +1,199 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hiendv/geojson/pkg/util"
+)
+
+const (
+	constTemplate = "{id}{variant}.{format}"
+	constFormat   = "geojson"
+	constStdout   = "-"
+)
+
+// templatePlaceholder matches a placeholder of a naming template, e.g. "{id}".
+var templatePlaceholder = regexp.MustCompile(`{([a-z]+)}`)
+
+// templateAmbiguous matches a variant next to a placeholder which is unknown when outputs are looked up.
+// Without a separator outside of slugs and variants, e.g. ".", "{name}{variant}" of "1-foo" would match "1-foo-rewind" as well.
+var templateAmbiguous = regexp.MustCompile(`{(name|date)}([a-z0-9_-]|{[a-z]+})*{variant}|{variant}([a-z0-9_-]|{[a-z]+})*{(name|date)}`)
+
+// templateKeys are the placeholders a naming template may use.
+var templateKeys = map[string]bool{
+	"id":      true, // ID of the relation
+	"parent":  true, // ID of the parent relation, which is the ID itself for merged outputs
+	"name":    true, // slug of the relation name, falling back to the ID
+	"variant": true, // options changing the output, e.g. "-vi_en-rewind"
+	"format":  true, // file extension
+	"date":    true, // date of the run in UTC, e.g. "2020-08-19"
+}
+
+// Template names output files relatively to the output directory, e.g. "{parent}/{name}.{id}{variant}.{format}".
+type Template string
+
+// ParseTemplate validates a naming template. An empty template means "{id}{variant}.{format}".
+func ParseTemplate(str string) (Template, error) {
+	if str == "" {
+		return constTemplate, nil
+	}
+
+	if strings.Count(str, "{") != len(templatePlaceholder.FindAllString(str, -1)) {
+		return "", fmt.Errorf("invalid template: %s", str)
+	}
+
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(str, -1) {
+		if !templateKeys[match[1]] {
+			return "", fmt.Errorf("invalid template placeholder: %s", match[0])
+		}
+	}
+
+	if templateAmbiguous.MatchString(str) {
+		return "", fmt.Errorf("invalid template, {variant} must be separated from {name} and {date} by a character other than letters, digits, \"-\" and \"_\": %s", str)
+	}
+
+	// outputs of different relations must not collide
+	if !strings.Contains(str, "{id}") {
+		return "", fmt.Errorf("invalid template without {id}: %s", str)
+	}
+
+	if filepath.IsAbs(str) {
+		return "", fmt.Errorf("invalid absolute template: %s", str)
+	}
+
+	for _, element := range strings.Split(filepath.ToSlash(str), "/") {
+		if element == "" || element == "." || element == ".." {
+			return "", fmt.Errorf("invalid template path: %s", str)
+		}
+	}
+
+	return Template(str), nil
+}
+
+// expand replaces placeholders with the values. Missing values are replaced by nothing.
+func (template Template) expand(values map[string]string) string {
+	return templatePlaceholder.ReplaceAllStringFunc(string(template), func(placeholder string) string {
+		return values[placeholder[1:len(placeholder)-1]]
+	})
+}
+
+// templateValues returns the placeholder values of a relation output.
+func templateValues(ctx context.Context, id int64, name string) map[string]string {
+	parent := id
+	root, ok := ctxRoot(ctx)
+	if ok && root != nil {
+		parent = int64(root.ID)
+	}
+
+	slug := util.Slugify(name, "")
+	if slug == "" {
+		slug = fmt.Sprint(id)
+	}
+
+	variant := ""
+	langs := ctxLanguages(ctx)
+	if len(langs) != 0 {
+		variant = fmt.Sprintf("%s-%s", variant, strings.Join(langs, "_"))
+	}
+
+	shouldRewind := ctxShouldRewind(ctx)
+	if shouldRewind {
+		variant = fmt.Sprintf("%s-rewind", variant)
+	}
+
+	// snapshots must not overwrite the current outputs
+	version, isVersion := ctxSnapshotVersion(ctx)
+	at, isSnapshot := ctxSnapshot(ctx)
+	if isVersion {
+		variant = fmt.Sprintf("%s-v%d", variant, version)
+	} else if isSnapshot {
+		variant = fmt.Sprintf("%s-%s", variant, snapshotLabel(at))
+	}
+
+	return map[string]string{
+		"id":      fmt.Sprint(id),
+		"parent":  fmt.Sprint(parent),
+		"name":    slug,
+		"variant": variant,
+		"format":  constFormat,
+		"date":    time.Now().UTC().Format("2006-01-02"),
+	}
+}
+
+// snapshotLabel formats the time of a snapshot, e.g. "20190101" or "20190101T120000Z".
+func snapshotLabel(at time.Time) string {
+	at = at.UTC()
+	if at.Equal(at.Truncate(24 * time.Hour)) {
+		return at.Format("20060102")
+	}
+
+	return at.Format("20060102T150405Z")
+}
+
+// filePath names the output of a relation by the naming template.
+func filePath(ctx context.Context, id int64, name string) (string, bool) {
+	dir, ok := ctxOutDir(ctx)
+	if !ok {
+		return "", false
+	}
+
+	template := ctxTemplate(ctx)
+	return filepath.Join(dir, filepath.FromSlash(template.expand(templateValues(ctx, id, name)))), true
+}
+
+// globFilePath looks for the output of a relation whose name and date are unknown.
+// The newest match is taken so the latest date or name wins over outputs left by previous runs.
+func globFilePath(ctx context.Context, id int64) (string, bool) {
+	dir, ok := ctxOutDir(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := templateValues(ctx, id, "")
+	for key, value := range values {
+		values[key] = globEscape(value)
+	}
+
+	values["name"], values["date"] = "*", "*"
+
+	template := ctxTemplate(ctx)
+	pattern := filepath.Join(globEscape(dir), filepath.FromSlash(template.expand(values)))
+	matches, err := filepath.Glob(pattern)
+	if err != nil || len(matches) == 0 {
+		return "", false
+	}
+
+	newest, newestTime := "", time.Time{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		// ties are broken by names so the lookup is deterministic
+		if newest == "" || info.ModTime().After(newestTime) || (info.ModTime().Equal(newestTime) && match > newest) {
+			newest, newestTime = match, info.ModTime()
+		}
+	}
+
+	return newest, newest != ""
+}
+
+func globEscape(str string) string {
+	var b strings.Builder
+	for _, r := range str {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
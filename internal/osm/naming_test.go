@@ -0,0 +1,118 @@
+package osm
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+)
+
+func TestParseTemplate(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		str  string
+		want Template
+		ok   bool
+	}{
+		{"", constTemplate, true},
+		{"{id}.json", "{id}.json", true},
+		{"{parent}/{name}.{id}{variant}.{format}", "{parent}/{name}.{id}{variant}.{format}", true},
+		{"{date}/{id}{variant}.{format}", "{date}/{id}{variant}.{format}", true},
+		{"{id}{variant}/{name}.{format}", "{id}{variant}/{name}.{format}", true},
+		{"{name}.{format}", "", false},
+		{"{id}{unknown}.{format}", "", false},
+		{"{id{variant}.{format}", "", false},
+		{"/tmp/{id}.{format}", "", false},
+		{"../{id}.{format}", "", false},
+		{"{parent}//{id}.{format}", "", false},
+		// lookups couldn't tell the name from the variant
+		{"{id}-{name}{variant}.{format}", "", false},
+		{"{name}-{id}{variant}.{format}", "", false},
+		{"{id}{variant}-{name}.{format}", "", false},
+		{"{id}-{date}_{variant}.{format}", "", false},
+	}
+
+	for _, test := range tests {
+		template, err := ParseTemplate(test.str)
+		is.Equal(err == nil, test.ok) // ParseTemplate(test.str)
+		is.Equal(template, test.want)
+	}
+}
+
+func TestTemplateValues(t *testing.T) {
+	is := is.New(t)
+
+	ctx := CtxSetRoot(context.Background(), &osm.Relation{ID: 49915})
+	values := templateValues(ctx, 1903516, "Thành phố Hà Nội")
+	is.Equal(values["id"], "1903516")
+	is.Equal(values["parent"], "49915")
+	is.Equal(values["name"], "thanh-pho-ha-noi")
+	is.Equal(values["variant"], "")
+	is.Equal(values["format"], constFormat)
+
+	is.Equal(templateValues(context.Background(), 1, "")["name"], "1")
+
+	ctx = CtxSetRewind(CtxSetLanguages(context.Background(), []string{"vi", "en"}), true)
+	is.Equal(templateValues(ctx, 1, "")["variant"], "-vi_en-rewind")
+
+	at := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	is.Equal(templateValues(CtxSetSnapshot(context.Background(), at, 0), 1, "")["variant"], "-20190101")
+	is.Equal(templateValues(CtxSetSnapshot(context.Background(), time.Time{}, 42), 1, "")["variant"], "-v42")
+
+	is.Equal(snapshotLabel(at.Add(12*time.Hour)), "20190101T120000Z")
+}
+
+func TestFilePath(t *testing.T) {
+	is := is.New(t)
+
+	_, ok := filePath(context.Background(), 1, "")
+	is.True(!ok)
+
+	ctx := CtxSetTemplate(context.WithValue(context.Background(), ctxKeyOut, "geo"), "{parent}/{name}.{id}{variant}.{format}")
+	ctx = CtxSetRoot(ctx, &osm.Relation{ID: 49915})
+	path, ok := filePath(ctx, 1903516, "Hà Nội")
+	is.True(ok)
+	is.Equal(path, filepath.Join("geo", "49915", "ha-noi.1903516.geojson"))
+}
+
+func TestGlobFilePath(t *testing.T) {
+	is := is.New(t)
+
+	dir := testDir(t)
+	ctx := CtxSetTemplate(context.WithValue(context.Background(), ctxKeyOut, dir), "{name}.{id}{variant}.{format}")
+
+	_, ok := globFilePath(ctx, 1)
+	is.True(!ok)
+
+	now := time.Now()
+	touch := func(name string, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		is.NoErr(ioutil.WriteFile(path, nil, 0644))
+		is.NoErr(os.Chtimes(path, modTime, modTime))
+	}
+
+	touch("ha-noi.1.geojson", now.Add(-time.Hour))
+	touch("ha-noi.1-rewind.geojson", now)
+	touch("ha-noi.1-vi_en.geojson", now)
+	touch("ha-noi.11.geojson", now)
+
+	path, ok := globFilePath(ctx, 1)
+	is.True(ok)
+	is.Equal(path, filepath.Join(dir, "ha-noi.1.geojson"))
+
+	// the relation was renamed after the previous run
+	touch("thanh-pho-ha-noi.1.geojson", now)
+	path, ok = globFilePath(ctx, 1)
+	is.True(ok)
+	is.Equal(path, filepath.Join(dir, "thanh-pho-ha-noi.1.geojson"))
+
+	path, ok = globFilePath(CtxSetRewind(ctx, true), 1)
+	is.True(ok)
+	is.Equal(path, filepath.Join(dir, "ha-noi.1-rewind.geojson"))
+}
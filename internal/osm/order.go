@@ -0,0 +1,76 @@
+package osm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+type orderedFeature struct {
+	id      int64 // sub-area ID
+	feature *geojson.Feature
+}
+
+// sortFeatures orders features by a property, then by their sub-area IDs so outputs are reproducible.
+// Features missing the property come last. An empty property orders by sub-area IDs only.
+// Property keys need no ordering because maps are encoded in the order of their keys.
+func sortFeatures(features []orderedFeature, property string) []*geojson.Feature {
+	sort.SliceStable(features, func(i, j int) bool {
+		if property != "" {
+			vi, oki := featureProperty(features[i].feature, property)
+			vj, okj := featureProperty(features[j].feature, property)
+			if oki != okj {
+				return oki
+			}
+
+			if oki {
+				c := compareValues(vi, vj)
+				if c != 0 {
+					return c < 0
+				}
+			}
+		}
+
+		return features[i].id < features[j].id
+	})
+
+	result := make([]*geojson.Feature, 0, len(features))
+	for _, f := range features {
+		result = append(result, f.feature)
+	}
+
+	return result
+}
+
+// compareValues compares numbers numerically and other values by their string forms.
+func compareValues(a, b interface{}) int {
+	fa, oka := toFloat(a)
+	fb, okb := toFloat(b)
+	if oka && okb {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,78 @@
+package osm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+func TestSortFeatures(t *testing.T) {
+	is := is.New(t)
+
+	feature := func(id string, properties map[string]interface{}) *geojson.Feature {
+		f := newTestFeature(id, map[string]string{})
+		for key, value := range properties {
+			f.Properties[key] = value
+		}
+
+		return f
+	}
+
+	a := feature("relation/3", map[string]interface{}{"name": "Hà Nội", "admin_level": int64(4)})
+	b := feature("relation/1", map[string]interface{}{"name": "Bắc Ninh", "admin_level": 10.0})
+	c := feature("relation/2", map[string]interface{}{"name": "Cần Thơ", "admin_level": int64(4)})
+	d := feature("relation/4", nil)
+	ordered := func() []orderedFeature {
+		return []orderedFeature{{id: 3, feature: a}, {id: 4, feature: d}, {id: 1, feature: b}, {id: 2, feature: c}}
+	}
+
+	tests := []struct {
+		property string
+		want     []*geojson.Feature
+	}{
+		{"", []*geojson.Feature{b, c, a, d}},
+		{"name", []*geojson.Feature{b, c, a, d}},
+		// numbers are compared numerically, then by sub-area IDs
+		{"admin_level", []*geojson.Feature{c, a, b, d}},
+		{"missing", []*geojson.Feature{b, c, a, d}},
+	}
+
+	for _, test := range tests {
+		is.Equal(sortFeatures(ordered(), test.property), test.want) // sortFeatures by test.property
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(compareValues(int64(2), 10.0), -1)
+	is.Equal(compareValues(10, int64(2)), 1)
+	is.Equal(compareValues(2.0, int64(2)), 0)
+	is.Equal(compareValues("10", "2"), -1)
+	is.Equal(compareValues("b", "a"), 1)
+	is.Equal(compareValues(true, "true"), 0)
+}
+
+func TestSortFeaturesFilteredTags(t *testing.T) {
+	is := is.New(t)
+
+	filter, err := NewTagFilter([]string{"name"}, false)
+	is.NoErr(err)
+	ctx := CtxSetSort(CtxSetTags(context.WithValue(context.Background(), ctxKeyRaw, true), filter), "ISO3166-2")
+
+	feature := func(id string, name string, code string) *geojson.Feature {
+		tags := filterTags(ctx, osm.Tags{{Key: "name", Value: name}, {Key: "ISO3166-2", Value: code}, {Key: "wikidata", Value: "Q1858"}})
+		return newTestFeature(id, tags.Map())
+	}
+
+	hanoi := feature("relation/1", "Hà Nội", "VN-HN")
+	danang := feature("relation/2", "Đà Nẵng", "VN-DN")
+	_, ok := featureProperty(hanoi, "wikidata")
+	is.True(!ok) // the whitelist still applies
+
+	sorted := sortFeatures([]orderedFeature{{id: 1, feature: hanoi}, {id: 2, feature: danang}}, ctxSort(ctx))
+	is.Equal(sorted, []*geojson.Feature{danang, hanoi}) // sorted by the tag out of the whitelist
+}
@@ -0,0 +1,274 @@
+package osm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hiendv/geojson/pkg/util"
+)
+
+const (
+	constChecksumExt = ".sum"
+	constTmpExt      = ".tmp"
+)
+
+var errMissingChecksum = errors.New("missing checksum")
+
+// Checksum is the sidecar of an output which verifies its integrity.
+type Checksum struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// verified memoizes outputs whose checksums were verified, by their paths.
+var verified sync.Map
+
+type verifiedOutput struct {
+	size    int64
+	modTime time.Time
+}
+
+func checksumPath(path string) string {
+	return path + constChecksumExt
+}
+
+// writeOutput writes an output atomically along with its compressed, manifest and checksum sidecars.
+// A missing checksum sidecar means the output is incomplete.
+func writeOutput(path string, data []byte, encodings []string, manifest *Manifest) error {
+	// the stale sidecar must not verify the new output in case of crashes
+	err := os.Remove(checksumPath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = writeAtomic(path, data)
+	if err != nil {
+		return err
+	}
+
+	err = writeCompressed(path, data, encodings)
+	if err != nil {
+		return err
+	}
+
+	err = writeManifest(path, manifest)
+	if err != nil {
+		return err
+	}
+
+	err = writeChecksum(path, data)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(stalePath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// writeChecksum writes the checksum sidecar of an output, which marks it as complete.
+func writeChecksum(path string, data []byte) error {
+	sum := sha256.Sum256(data)
+	checksum, err := json.Marshal(Checksum{Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(checksumPath(path), checksum)
+}
+
+// adoptOutput writes the missing checksum sidecar of an output written before checksums were introduced.
+// Such outputs have no manifest either, and they are adopted only if they are valid JSON so truncated ones are still rejected.
+func adoptOutput(path string) error {
+	_, err := os.Stat(manifestPath(path))
+	if err == nil {
+		return errMissingChecksum
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !json.Valid(data) {
+		return errors.New("invalid output")
+	}
+
+	return writeChecksum(path, data)
+}
+
+// writeCompressed writes compressed sidecars of an output, e.g. "<output>.gz", and removes stale ones.
+func writeCompressed(path string, data []byte, encodings []string) error {
+	requested := map[string]bool{}
+	for _, encoding := range encodings {
+		requested[encoding] = true
+	}
+
+	for _, encoding := range []string{util.EncodingGzip, util.EncodingBrotli} {
+		sidecar := path + util.EncodingExtension(encoding)
+		if !requested[encoding] {
+			err := os.Remove(sidecar)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			continue
+		}
+
+		compressed, err := util.Compress(data, encoding, util.CompressionBest)
+		if err != nil {
+			return err
+		}
+
+		err = writeAtomic(sidecar, compressed)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeManifest writes the manifest sidecar of an output or removes the stale one.
+func writeManifest(path string, manifest *Manifest) error {
+	if manifest == nil {
+		err := os.Remove(manifestPath(path))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(manifestPath(path), data)
+}
+
+// writeAtomic writes to a temporary file in the same directory, syncs it, then renames it.
+// Readers see either the old file or the new one, never a truncated file.
+func writeAtomic(path string, data []byte) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%s.*%s", filepath.Base(path), constTmpExt))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			// nolint:errcheck
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0o644)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir persists renames within a directory.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// directories can't be synced on some platforms
+	// nolint:errcheck
+	d.Sync()
+	return nil
+}
+
+// verifyChecksum compares an output with its sidecar.
+// Checksums are computed once per version of the output.
+func verifyChecksum(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(checksumPath(path))
+	if os.IsNotExist(err) {
+		return errMissingChecksum
+	}
+
+	if err != nil {
+		return err
+	}
+
+	checksum := Checksum{}
+	err = json.Unmarshal(data, &checksum)
+	if err != nil {
+		return errors.New("invalid checksum")
+	}
+
+	if info.Size() != checksum.Size {
+		return errors.New("size mismatch")
+	}
+
+	current := verifiedOutput{size: info.Size(), modTime: info.ModTime()}
+	v, ok := verified.Load(path)
+	if ok && v.(verifiedOutput).size == current.size && v.(verifiedOutput).modTime.Equal(current.modTime) {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != checksum.SHA256 {
+		verified.Delete(path)
+		return errors.New("checksum mismatch")
+	}
+
+	verified.Store(path, current)
+	return nil
+}
@@ -0,0 +1,90 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiendv/geojson/pkg/geoutil"
+	"github.com/paulmach/orb/geojson"
+)
+
+const (
+	propCentroid   = "centroid"
+	propLabelPoint = "label_point"
+	propArea       = "area_%s2"
+	propPerimeter  = "perimeter_%s"
+)
+
+// MeasureUnit is the unit of geodesic measurements.
+type MeasureUnit string
+
+const (
+	// MeasureNone skips the measurements.
+	MeasureNone MeasureUnit = ""
+	// MeasureMeter measures in m and m².
+	MeasureMeter MeasureUnit = "m"
+	// MeasureKilometer measures in km and km².
+	MeasureKilometer MeasureUnit = "km"
+)
+
+// ParseMeasureUnit interprets a string as a MeasureUnit.
+func ParseMeasureUnit(str string) (MeasureUnit, error) {
+	switch unit := MeasureUnit(str); unit {
+	case MeasureNone, MeasureMeter, MeasureKilometer:
+		return unit, nil
+	default:
+		return MeasureNone, fmt.Errorf("invalid measure unit: %s", str)
+	}
+}
+
+// scale returns the number of meters in the unit.
+func (unit MeasureUnit) scale() float64 {
+	if unit == MeasureKilometer {
+		return 1000
+	}
+
+	return 1
+}
+
+// annotateFeatureCollection adds computed properties to features.
+func annotateFeatureCollection(ctx context.Context, fc *geojson.FeatureCollection) error {
+	shouldCentroid := ctxShouldCentroid(ctx)
+	shouldLabel := ctxShouldLabelPoint(ctx)
+	unit := ctxMeasure(ctx)
+	for _, feature := range fc.Features {
+		if shouldCentroid {
+			centroid, err := geoutil.Centroid(feature.Geometry)
+			if err != nil {
+				return fmt.Errorf("could not compute centroid of %v: %w", feature.ID, err)
+			}
+
+			feature.Properties[propCentroid] = centroid
+		}
+
+		if shouldLabel {
+			point, err := geoutil.LabelPoint(feature.Geometry)
+			if err != nil {
+				return fmt.Errorf("could not compute label point of %v: %w", feature.ID, err)
+			}
+
+			feature.Properties[propLabelPoint] = point
+		}
+
+		if unit != MeasureNone {
+			area, err := geoutil.GeodesicArea(feature.Geometry)
+			if err != nil {
+				return fmt.Errorf("could not compute area of %v: %w", feature.ID, err)
+			}
+
+			perimeter, err := geoutil.GeodesicPerimeter(feature.Geometry)
+			if err != nil {
+				return fmt.Errorf("could not compute perimeter of %v: %w", feature.ID, err)
+			}
+
+			feature.Properties[fmt.Sprintf(propArea, unit)] = area / unit.scale() / unit.scale()
+			feature.Properties[fmt.Sprintf(propPerimeter, unit)] = perimeter / unit.scale()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package osm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+const (
+	constStaleExt = ".stale"
+	// constReplicationBatch is the number of diffs applied at once, so a long outage catches up gradually.
+	constReplicationBatch = 60
+	// constReplicationAttempts is the number of calls trying to read a diff before it's skipped.
+	constReplicationAttempts = 3
+)
+
+// Replication reads osmChange diffs of a replication directory, e.g. "https://planet.osm.org/replication/minute" or a local mirror of it.
+type Replication struct {
+	source   string
+	client   *http.Client
+	seq      uint64 // the last applied diff
+	attempts int    // failed reads of the next diff
+}
+
+// NewReplication constructs a consumer of the replication directory at the URL or the local path.
+func NewReplication(source string) (*Replication, error) {
+	if source == "" {
+		return nil, errors.New("invalid replication source")
+	}
+
+	return &Replication{
+		source: strings.TrimSuffix(source, "/"),
+		client: &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (r *Replication) remote() bool {
+	return strings.HasPrefix(r.source, "http://") || strings.HasPrefix(r.source, "https://")
+}
+
+func (r *Replication) read(ctx context.Context, name string) ([]byte, error) {
+	if !r.remote() {
+		return ioutil.ReadFile(filepath.Join(r.source, filepath.FromSlash(name)))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.source+"/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d of %s", resp.StatusCode, req.URL)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// state returns the sequence number of the latest diff.
+func (r *Replication) state(ctx context.Context) (uint64, error) {
+	data, err := r.read(ctx, "state.txt")
+	if err != nil {
+		return 0, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "sequenceNumber" {
+			return strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+		}
+	}
+
+	return 0, errors.New("missing sequence number")
+}
+
+// change reads a diff, e.g. "000/123/456.osc.gz" for the sequence number 123456.
+func (r *Replication) change(ctx context.Context, seq uint64) (*osm.Change, error) {
+	digits := fmt.Sprintf("%09d", seq)
+	data, err := r.read(ctx, fmt.Sprintf("%s/%s/%s.osc.gz", digits[:3], digits[3:6], digits[6:]))
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	change := &osm.Change{}
+	err = xml.NewDecoder(reader).Decode(change)
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// Apply reads the diffs published since the last call and marks the outputs they touch as stale.
+// The first call only looks up the latest diff and marks the outputs which can't be looked up as stale. Paths of the stale outputs are returned, even along with an error.
+// A diff which can't be read is tried again by the next calls, then skipped so the replication doesn't stall.
+func (r *Replication) Apply(ctx context.Context) ([]string, error) {
+	log := ctxLog(ctx)
+	latest, err := r.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.seq == 0 {
+		log.Infow("replication started", "seq", latest)
+		r.seq = latest
+		// outputs without manifests can't be looked up by the diffs, so they're regenerated once
+		return invalidateOutputs(ctx, true, nil, nil, nil)
+	}
+
+	if latest < r.seq {
+		log.Infow("replication restarted", "seq", latest)
+		r.seq = latest
+		return nil, nil
+	}
+
+	end := latest
+	if end > r.seq+constReplicationBatch {
+		end = r.seq + constReplicationBatch
+	}
+
+	var failure error
+	relations, ways, nodes := map[int64]bool{}, map[int64]bool{}, map[int64]bool{}
+	for r.seq < end {
+		seq := r.seq + 1
+		change, err := r.change(ctx, seq)
+		if err != nil {
+			r.attempts++
+			if r.attempts < constReplicationAttempts {
+				failure = fmt.Errorf("could not read diff %d: %w", seq, err)
+				break
+			}
+
+			// changes of the diff are lost, outputs they touch are regenerated only once they change again
+			log.Errorw("replication diff skipped", "seq", seq, "attempts", r.attempts, "error", err)
+			r.attempts = 0
+			r.seq = seq
+			continue
+		}
+
+		for _, o := range []*osm.OSM{change.Create, change.Modify, change.Delete} {
+			if o == nil {
+				continue
+			}
+
+			for _, relation := range o.Relations {
+				relations[int64(relation.ID)] = true
+			}
+
+			for _, way := range o.Ways {
+				ways[int64(way.ID)] = true
+			}
+
+			for _, node := range o.Nodes {
+				nodes[int64(node.ID)] = true
+			}
+		}
+
+		log.Debugw("replication applied", "seq", seq, "relations", len(relations), "ways", len(ways), "nodes", len(nodes))
+		r.attempts = 0
+		r.seq = seq
+	}
+
+	// outputs are walked only if any diff was applied
+	if len(relations) == 0 && len(ways) == 0 && len(nodes) == 0 {
+		return nil, failure
+	}
+
+	stale, err := invalidateOutputs(ctx, false, relations, ways, nodes)
+	if err != nil {
+		return stale, err
+	}
+
+	return stale, failure
+}
+
+// invalidateOutputs marks outputs built from the relations, the ways or the nodes as stale, along with the cached responses of those relations.
+// Outputs are looked up through their manifests. Outputs without manifests, or with manifests which don't record nodes, can't be looked up so they are stale only if untracked is set.
+func invalidateOutputs(ctx context.Context, untracked bool, relations map[int64]bool, ways map[int64]bool, nodes map[int64]bool) ([]string, error) {
+	dir, ok := ctxOutDir(ctx)
+	if !ok {
+		return nil, errors.New("invalid directory")
+	}
+
+	stale := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// checkpoint parts and dotfiles aren't outputs
+		rel, _ := filepath.Rel(dir, path)
+		if info.IsDir() && rel != "." && (Internal(rel) || strings.HasSuffix(path, constPartsExt)) {
+			return filepath.SkipDir
+		}
+
+		if info.IsDir() || Internal(rel) || !strings.HasSuffix(path, "."+constFormat) || isStale(path) {
+			return nil
+		}
+
+		touched, err := touchedOutput(ctx, path, untracked, relations, ways, nodes)
+		if err != nil || !touched {
+			return err
+		}
+
+		stale = append(stale, path)
+		return markStale(path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stale) != 0 {
+		ctxLog(ctx).Infow("stale outputs", "total", len(stale), "paths", stale)
+	}
+
+	return stale, nil
+}
+
+// touchedOutput determines if an output is built from any of the objects through its manifest, or can't be looked up if untracked is set.
+// The cached responses of the touched sub-areas are dropped.
+func touchedOutput(ctx context.Context, path string, untracked bool, relations map[int64]bool, ways map[int64]bool, nodes map[int64]bool) (bool, error) {
+	data, err := ioutil.ReadFile(manifestPath(path))
+	if os.IsNotExist(err) {
+		return untracked, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	manifest := Manifest{}
+	if json.Unmarshal(data, &manifest) != nil {
+		return untracked, nil
+	}
+
+	cache := ctxResponseCache(ctx)
+	touched := manifest.Parent != nil && relations[manifest.Parent.ID]
+	for _, subArea := range manifest.SubAreas {
+		changed := relations[subArea.ID] || (untracked && subArea.Nodes == nil)
+		for way := range subArea.Ways {
+			changed = changed || ways[way]
+		}
+
+		for node := range subArea.Nodes {
+			changed = changed || nodes[node]
+		}
+
+		if !changed {
+			continue
+		}
+
+		touched = true
+		cache.remove(osm.TypeRelation, subArea.ID)
+	}
+
+	return touched, nil
+}
+
+func stalePath(path string) string {
+	return path + constStaleExt
+}
+
+// markStale marks an output to be regenerated. The marker is removed once the output is written again.
+func markStale(path string) error {
+	return writeAtomic(stalePath(path), []byte(time.Now().UTC().Format(time.RFC3339)))
+}
+
+func isStale(path string) bool {
+	_, err := os.Stat(stalePath(path))
+	return err == nil
+}
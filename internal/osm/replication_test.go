@@ -0,0 +1,120 @@
+package osm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+)
+
+func writeTestDiff(t *testing.T, dir string, name string, change *osm.Change) {
+	data, err := xml.Marshal(change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err = w.Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(path, b.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestOutput(t *testing.T, dir string, name string, manifest *Manifest) string {
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, []byte(`{"type":"FeatureCollection","features":[]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if manifest == nil {
+		return path
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(manifestPath(path), data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestReplicationApply(t *testing.T) {
+	is := is.New(t)
+
+	out, source := testDir(t), testDir(t)
+	moved := writeTestOutput(t, out, "11.geojson", &Manifest{SubAreas: []SubAreaManifest{
+		{ID: 11, Ways: map[int64]int{101: 1}, Nodes: map[int64]int{1001: 1}},
+	}})
+	untouched := writeTestOutput(t, out, "12.geojson", &Manifest{SubAreas: []SubAreaManifest{
+		{ID: 12, Ways: map[int64]int{201: 1}, Nodes: map[int64]int{2001: 1}},
+	}})
+	legacy := writeTestOutput(t, out, "13.geojson", nil)
+	writeTestOutput(t, out, ".13.geojson.123.tmp", nil)
+
+	logger := &testLogger{}
+	ctx := newTestContext(logger, out, true, &osm.Relation{ID: 1})
+	replication, err := NewReplication(source)
+	is.NoErr(err)
+
+	is.NoErr(ioutil.WriteFile(filepath.Join(source, "state.txt"), []byte("sequenceNumber=1\n"), 0644))
+	stale, err := replication.Apply(ctx)
+	is.NoErr(err)
+	is.Equal(stale, []string{legacy}) // the first call only looks up the latest diff, outputs without manifests are stale once
+	is.Equal(replication.seq, uint64(1))
+
+	is.NoErr(os.Remove(stalePath(legacy))) // regenerated, e.g. by an older version
+
+	// the node moved without changing its way, the next diff is missing
+	is.NoErr(ioutil.WriteFile(filepath.Join(source, "state.txt"), []byte("sequenceNumber=3\n"), 0644))
+	writeTestDiff(t, source, "000/000/002.osc.gz", &osm.Change{Modify: &osm.OSM{Nodes: osm.Nodes{{ID: 1001, Version: 2}}}})
+	stale, err = replication.Apply(ctx)
+	is.True(err != nil) // the missing diff
+	sort.Strings(stale)
+	is.Equal(stale, []string{moved})
+	is.True(isStale(moved))
+	is.True(!isStale(untouched))
+	is.True(!isStale(legacy)) // outputs without manifests aren't stale on every call
+	is.Equal(replication.seq, uint64(2))
+
+	for attempt := 2; attempt < constReplicationAttempts; attempt++ {
+		_, err = replication.Apply(ctx)
+		is.True(err != nil) // retried
+		is.Equal(replication.seq, uint64(2))
+	}
+
+	_, err = replication.Apply(ctx)
+	is.NoErr(err)
+	is.Equal(replication.seq, uint64(3)) // skipped after the last attempt
+	is.Equal(logger.messages[len(logger.messages)-1], "replication diff skipped")
+}
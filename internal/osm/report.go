@@ -0,0 +1,132 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/paulmach/osm"
+)
+
+// ErrFailures is returned if more sub-areas failed than allowed.
+var ErrFailures = errors.New("too many failed sub-areas")
+
+// BatchReport is the machine-readable outcome of handling sub-areas of relations.
+type BatchReport struct {
+	Parents []*Report `json:"parents"`
+}
+
+// Report is the machine-readable outcome of handling sub-areas of a relation.
+type Report struct {
+	Parent    int64          `json:"parent"`
+	Error     string         `json:"error,omitempty"`   // the parent itself failed
+	Resumed   bool           `json:"resumed,omitempty"` // the parent was done by a previous run
+	Succeeded []MemberReport `json:"succeeded"`
+	Failed    []MemberReport `json:"failed"`
+	Skipped   []MemberReport `json:"skipped"`
+}
+
+// MemberReport is the outcome of a member of the relation.
+type MemberReport struct {
+	ID     int64        `json:"id"`
+	Type   osm.Type     `json:"type"`
+	Role   string       `json:"role"`
+	Status Completeness `json:"status,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// newReport lists handled sub-areas along with skipped members, ordered by their IDs.
+func newReport(parent int64, handled []subArea, skipped []osm.Member) *Report {
+	report := &Report{
+		Parent:    parent,
+		Succeeded: []MemberReport{},
+		Failed:    []MemberReport{},
+		Skipped:   []MemberReport{},
+	}
+
+	for _, result := range handled {
+		member := MemberReport{ID: result.id, Type: osm.TypeRelation, Role: constRoleSubArea}
+		if result.err != nil {
+			member.Error = result.err.Error()
+			report.Failed = append(report.Failed, member)
+			continue
+		}
+
+		member.Status = result.status
+		report.Succeeded = append(report.Succeeded, member)
+	}
+
+	for _, m := range skipped {
+		report.Skipped = append(report.Skipped, MemberReport{ID: m.Ref, Type: m.Type, Role: m.Role})
+	}
+
+	for _, members := range [][]MemberReport{report.Succeeded, report.Failed, report.Skipped} {
+		members := members
+		sort.Slice(members, func(i, j int) bool {
+			return members[i].ID < members[j].ID
+		})
+	}
+
+	return report
+}
+
+// writeReport writes the report if a path is given.
+func writeReport(ctx context.Context, report *BatchReport) error {
+	path := ctxReport(ctx)
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	ctxLog(ctx).Infow("writing report", "path", path)
+	return writeAtomic(path, data)
+}
+
+// tooManyFailures determines if the number of failed sub-areas breaks the policy.
+func tooManyFailures(ctx context.Context, failed int) bool {
+	allowed, ok := ctxAllowedFailures(ctx)
+	return ok && failed > allowed
+}
+
+// checkFailures returns ErrFailures if any parent of the report breaks the failure policy.
+func checkFailures(ctx context.Context, report *BatchReport) error {
+	allowed, _ := ctxAllowedFailures(ctx)
+	for _, parent := range report.Parents {
+		if !tooManyFailures(ctx, len(parent.Failed)) {
+			continue
+		}
+
+		return fmt.Errorf("%w: %d failed in %d, %d allowed", ErrFailures, len(parent.Failed), parent.Parent, allowed)
+	}
+
+	return nil
+}
+
+// summarizeBatch logs the aggregated outcome of all parents.
+func summarizeBatch(ctx context.Context, report *BatchReport) {
+	failedParents, succeeded, failed, skipped := 0, 0, 0, 0
+	for _, parent := range report.Parents {
+		if parent.Error != "" {
+			failedParents++
+		}
+
+		succeeded += len(parent.Succeeded)
+		failed += len(parent.Failed)
+		skipped += len(parent.Skipped)
+	}
+
+	ctxLog(ctx).Infow(
+		"parents handled",
+		"total", len(report.Parents),
+		"failed", failedParents,
+		"sub_areas_succeeded", succeeded,
+		"sub_areas_failed", failed,
+		"members_skipped", skipped,
+	)
+}
@@ -0,0 +1,94 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+)
+
+func newTestBatchReport() *BatchReport {
+	return &BatchReport{Parents: []*Report{
+		newReport(1, []subArea{
+			{id: 12, status: CompletenessComplete},
+			{id: 11, status: CompletenessPartial},
+			{id: 13, err: errors.New("timeout")},
+		}, []osm.Member{{Type: osm.TypeNode, Ref: 10, Role: "admin_centre"}}),
+		{Parent: 2, Error: "not found", Succeeded: []MemberReport{}, Failed: []MemberReport{}, Skipped: []MemberReport{}},
+	}}
+}
+
+func TestNewReport(t *testing.T) {
+	is := is.New(t)
+
+	report := newTestBatchReport().Parents[0]
+	is.Equal(report.Succeeded, []MemberReport{
+		{ID: 11, Type: osm.TypeRelation, Role: constRoleSubArea, Status: CompletenessPartial},
+		{ID: 12, Type: osm.TypeRelation, Role: constRoleSubArea, Status: CompletenessComplete},
+	})
+	is.Equal(report.Failed, []MemberReport{{ID: 13, Type: osm.TypeRelation, Role: constRoleSubArea, Error: "timeout"}})
+	is.Equal(report.Skipped, []MemberReport{{ID: 10, Type: osm.TypeNode, Role: "admin_centre"}})
+}
+
+func TestCheckFailures(t *testing.T) {
+	is := is.New(t)
+
+	report := newTestBatchReport()
+	tests := []struct {
+		allowed interface{}
+		failed  bool
+	}{
+		{nil, false},
+		{-1, false},
+		{1, false},
+		{0, true},
+	}
+
+	for _, test := range tests {
+		ctx := context.Background()
+		if test.allowed != nil {
+			ctx = CtxSetAllowedFailures(ctx, test.allowed.(int))
+		}
+
+		err := checkFailures(ctx, report)
+		is.Equal(errors.Is(err, ErrFailures), test.failed) // checkFailures with test.allowed
+	}
+
+	is.True(!tooManyFailures(CtxSetAllowedFailures(context.Background(), 2), 2))
+	is.True(tooManyFailures(CtxSetAllowedFailures(context.Background(), 2), 3))
+}
+
+func TestSummarizeBatch(t *testing.T) {
+	is := is.New(t)
+
+	logger := &testLogger{}
+	summarizeBatch(context.WithValue(context.Background(), ctxKeyLog, logger), newTestBatchReport())
+	is.Equal(logger.fields["parents handled"], []interface{}{
+		"total", 2,
+		"failed", 1,
+		"sub_areas_succeeded", 2,
+		"sub_areas_failed", 1,
+		"members_skipped", 1,
+	})
+}
+
+func TestWriteReport(t *testing.T) {
+	is := is.New(t)
+
+	is.NoErr(writeReport(context.Background(), newTestBatchReport()))
+
+	path := filepath.Join(testDir(t), "report.json")
+	is.NoErr(writeReport(CtxSetReport(context.Background(), path), newTestBatchReport()))
+
+	data, err := ioutil.ReadFile(path)
+	is.NoErr(err)
+
+	report := &BatchReport{}
+	is.NoErr(json.Unmarshal(data, report))
+	is.Equal(report, newTestBatchReport())
+}
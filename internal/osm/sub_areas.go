@@ -2,10 +2,9 @@ package osm
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"os"
 	"path/filepath"
 	"sync"
 	"time"
@@ -14,7 +13,6 @@ import (
 	"github.com/hiendv/geojson/pkg/util"
 	"github.com/paulmach/orb/geojson"
 	"github.com/paulmach/osm"
-	"github.com/paulmach/osm/osmapi"
 	"github.com/paulmach/osm/osmgeojson"
 )
 
@@ -22,42 +20,137 @@ const (
 	constRoleSubArea = "subarea"
 	constChannelCap  = 1000
 	constWorkerCap   = 10
+	constParentCap   = 4
 )
 
-var constTags = []string{"name", "type"} // slice isn't immutable by nature
-
 type subArea struct {
-	id   int64
-	fc   *geojson.FeatureCollection
-	json []byte
-	err  error
+	id      int64
+	fc      *geojson.FeatureCollection
+	json    []byte
+	err     error
+	name    string
+	version int
+	status  Completeness
+	ways    []int64 // offending ways
+	resumed bool    // done by a previous run
+
+	wayVersions  map[int64]int
+	nodeVersions map[int64]int
+	unchanged    bool // taken from the previous output
+}
+
+// job is a sub-area handled by the shared workers.
+type job struct {
+	ctx     context.Context // context of the parent
+	id      int64
+	results chan<- subArea
+	pending *sync.WaitGroup // pending sub-areas of the parent
 }
 
 // SubAreas constructs a GeoJSON output of an OpenStreetMap relation ID
 func SubAreas(ctx context.Context, str string) error {
+	id, err := util.Int64FromString(str)
+	if err != nil {
+		return err
+	}
+
+	return SubAreasBatch(ctx, []int64{id})
+}
+
+// SubAreasBatch constructs GeoJSON outputs of OpenStreetMap relation IDs.
+// Sub-areas of all parents are handled by shared workers while each parent gets its own output.
+func SubAreasBatch(ctx context.Context, ids []int64) error {
 	log := ctxLog(ctx)
-	log.Debugw("constants", "channel_cap", constChannelCap, "worker_cap", constWorkerCap)
+	log.Debugw("constants", "channel_cap", constChannelCap, "worker_cap", constWorkerCap, "parent_cap", constParentCap)
 
-	id, err := util.Int64FromString(str)
+	var handler, parents sync.WaitGroup
+	jobs := make(chan job, constChannelCap)
+
+	// creating workers for handling
+	for i := 0; i < constWorkerCap; i++ {
+		handler.Add(1)
+		go handleJobs(&handler, jobs)
+	}
+
+	// parents are fetched concurrently up to the cap
+	reports := make([]*Report, len(ids))
+	errs := make([]error, len(ids))
+	slots := make(chan struct{}, constParentCap)
+	for i, id := range ids {
+		parents.Add(1)
+		go func(i int, id int64) {
+			defer parents.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if ctxCheckpoint(ctx).parentDone(id) {
+				log.Infow("parent done already", "parent", id)
+				reports[i] = newReport(id, ctxCheckpoint(ctx).handled(id), nil)
+				reports[i].Resumed = true
+				return
+			}
+
+			reports[i], errs[i] = subAreas(ctx, id, jobs)
+		}(i, id)
+	}
+
+	// main flow
+	parents.Wait()
+	close(jobs)
+
+	handler.Wait()
+
+	report := &BatchReport{Parents: reports}
+	summarizeBatch(ctx, report)
+
+	err := writeReport(ctx, report)
 	if err != nil {
 		return err
 	}
 
+	// errors of a single parent are returned as they are, e.g. for ErrIsClient
+	if len(ids) == 1 && errs[0] != nil {
+		return errs[0]
+	}
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d parents failed", failed, len(ids))
+	}
+
+	return checkFailures(ctx, report)
+}
+
+// subAreas fetches a parent and pushes its sub-areas to the shared workers.
+func subAreas(ctx context.Context, id int64, jobs chan<- job) (*Report, error) {
+	log := ctxLog(ctx)
 	log.Infow("fetching sub-areas", "parent", id)
 
 	// querying the relation
-	relation, err := osmapi.Relation(ctx, osm.RelationID(id))
+	relation, err := fetchParent(ctx, id)
 	if err != nil || relation == nil {
-		return err
+		report := newReport(id, nil, nil)
+		if err != nil {
+			log.Errorw("parent failed", "parent", id, "error", err)
+			report.Error = err.Error()
+		}
+
+		return report, err
 	}
 
-	log.Debugw("sub-areas fetched", "total", len(relation.Members))
+	log.Debugw("sub-areas fetched", "parent", id, "total", len(relation.Members))
 
 	members := []osm.Member{}
-
-	// creating workers for pushing
+	skipped := []osm.Member{}
 	for _, member := range relation.Members {
 		if member.Role != constRoleSubArea {
+			skipped = append(skipped, member)
 			continue
 		}
 
@@ -65,97 +158,134 @@ func SubAreas(ctx context.Context, str string) error {
 	}
 
 	if len(members) == 0 {
-		log.Warnw("sub-areas matched", "total", 0)
-		return nil
+		log.Warnw("sub-areas matched", "parent", id, "total", 0)
+		return newReport(id, nil, skipped), nil
 	}
 
-	log.Debugw("sub-areas matched", "total", len(members))
+	log.Debugw("sub-areas matched", "parent", id, "total", len(members))
 
 	ctx = CtxSetRoot(ctx, relation)
+	_, ok := ctxSnapshotVersion(ctx)
+	if ok {
+		// members are fetched as they were at the version of the parent
+		ctx = context.WithValue(ctx, ctxKeyAt, relation.Timestamp)
+	}
+	if ctxShouldUpdate(ctx) && ctxShouldCombine(ctx) {
+		previous, _ := readPreviousOutput(ctx, id)
+		ctx = ctxSetPreviousOutput(ctx, previous)
+	}
+
+	checkpoint := ctxCheckpoint(ctx)
+	err = checkpoint.record(CheckpointEntry{Parent: id, Version: relation.Version, Status: CheckpointStarted})
+	if err != nil {
+		return newReport(id, nil, skipped), err
+	}
 
-	var pusher, handler, reporter sync.WaitGroup
-	ids := make(chan int64, constChannelCap)
+	var pusher, pending, reporter sync.WaitGroup
 	results := make(chan subArea, constChannelCap)
+	handled := []subArea{}
 
+	var reportErr error
 	reporter.Add(1) // spawn once only
-	go reportResults(ctx, &reporter, results)
-
-	// creating workers for handling
-	for i := 0; i < constWorkerCap; i++ {
-		handler.Add(1)
-		go handleMembers(ctx, &handler, ids, results)
-	}
+	go func() {
+		defer reporter.Done()
+		reportErr = reportResults(ctx, results, &handled)
+	}()
 
 	// creating workers for pushing
 	for _, member := range members {
+		result, ok := resumeSubArea(ctx, id, member.Ref)
+		if ok {
+			log.Debugw("sub-area done already", "id", member.Ref)
+			results <- result
+			continue
+		}
+
 		pusher.Add(1)
-		go pushMember(ctx, &pusher, ids, member.Ref)
+		pending.Add(1)
+		go pushMember(ctx, &pusher, jobs, job{ctx: ctx, id: member.Ref, results: results, pending: &pending})
 	}
 
-	// main flow
 	pusher.Wait()
-	close(ids)
-
-	handler.Wait()
+	pending.Wait()
 	close(results)
 
 	reporter.Wait()
 
-	log.Infow("sub-areas handled", "total", len(members))
-	return nil
+	summarize(ctx, id, handled)
+	report := newReport(id, handled, skipped)
+	if reportErr != nil {
+		log.Errorw("parent failed", "parent", id, "error", reportErr)
+		report.Error = reportErr.Error()
+	}
+
+	return report, reportErr
 }
 
-func handleMembers(ctx context.Context, wg *sync.WaitGroup, ids <-chan int64, results chan<- subArea) {
+func handleJobs(wg *sync.WaitGroup, jobs <-chan job) {
 	defer wg.Done()
 
-	for id := range ids {
-		fc, b, err := handleMember(ctx, id)
-		results <- subArea{id, fc, b, err}
+	for j := range jobs {
+		j.results <- handleMember(j.ctx, j.id)
+		j.pending.Done()
 	}
 }
 
-func handleMember(ctx context.Context, id int64) (*geojson.FeatureCollection, []byte, error) {
+func handleMember(ctx context.Context, id int64) subArea {
 	log := ctxLog(ctx)
 	defer func() {
 		log.Debugw("sub-area handled", "id", id)
 	}()
+
+	result := subArea{id: id}
+	var osmObject *osm.OSM
+	if ctxShouldUpdate(ctx) {
+		reused, current, ok := reuseSubArea(ctx, id)
+		if ok {
+			return reused
+		}
+
+		osmObject = current
+	}
+
 	// querying the full relation of a sub-area
-	osmObject, err := osmapi.RelationFull(ctx, osm.RelationID(id))
-	if err != nil {
-		return nil, nil, err
+	if osmObject == nil {
+		var err error
+		osmObject, err = fetchRelationFull(ctx, id)
+		if err != nil {
+			result.err = err
+			return result
+		}
 	}
 
-	shouldNormalize := ctxShouldNormalize(ctx)
 	shouldCombine := ctxShouldCombine(ctx)
 	shouldRewind := ctxShouldRewind(ctx)
 
 	// whitelisting tags
 	for _, relation := range osmObject.Relations {
-		tags := osm.Tags{}
-		for _, tagName := range constTags {
-			var newTag osm.Tag
-			tag := osm.Tag{Key: tagName, Value: relation.Tags.Find(tagName)}
-			if shouldNormalize {
-				newTag = tag
-				newTag.Value = util.NormalizeString(tag.Value)
-			}
+		if int64(relation.ID) == id {
+			result.name = relation.Tags.Find("name")
+			result.version = relation.Version
+		}
 
-			if tag.Value == newTag.Value {
-				tags = append(tags, tag)
-				continue
-			}
+		relation.Tags = filterTags(ctx, relation.Tags)
+	}
 
-			tag.Key = fmt.Sprintf("%s:original", tag.Key)
-			tags = append(tags, tag, newTag)
-		}
+	result.wayVersions = map[int64]int{}
+	for _, way := range osmObject.Ways {
+		result.wayVersions[int64(way.ID)] = way.Version
+	}
 
-		relation.Tags = tags
+	result.nodeVersions = map[int64]int{}
+	for _, node := range osmObject.Nodes {
+		result.nodeVersions[int64(node.ID)] = node.Version
 	}
 
 	// converting from OSM to GeoJSON
-	featureCollection, err := osmgeojson.Convert(osmObject, osmgeojson.NoMeta(true))
+	featureCollection, err := osmgeojson.Convert(osmObject, osmgeojson.NoMeta(!ctxShouldMeta(ctx)))
 	if err != nil {
-		return nil, nil, err
+		result.err = err
+		return result
 	}
 
 	// cleaning up everything but the relation itself
@@ -174,32 +304,69 @@ func handleMember(ctx context.Context, id int64) (*geojson.FeatureCollection, []
 	}
 
 	featureCollection.Features = features
+	mapFeatureCollection(ctx, featureCollection)
+	result.fc = featureCollection
+	result.status, result.ways = checkIntegrity(osmObject, id, features)
+
+	precision, shouldQuantize := ctxPrecision(ctx)
+	if shouldQuantize {
+		err := geoutil.QuantizeFeatureCollection(featureCollection, precision)
+		if err != nil {
+			result.err = err
+			return result
+		}
+	}
+
+	validation := ctxValidation(ctx)
+	if validation != ValidationNone {
+		err := validateFeatureCollection(ctx, featureCollection, validation)
+		if err != nil {
+			result.err = err
+			return result
+		}
+	}
+
 	if shouldRewind {
 		err := geoutil.RewindFeatureCollection(featureCollection, false)
 		if err != nil {
-			return featureCollection, nil, err
+			result.err = err
+			return result
 		}
 	}
 
-	if shouldCombine {
-		return featureCollection, nil, err
+	err = geoutil.BBoxFeatureCollection(featureCollection)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	err = annotateFeatureCollection(ctx, featureCollection)
+	if err != nil {
+		result.err = err
+		return result
 	}
 
-	featureCollectionJSON, err := json.Marshal(featureCollection)
+	err = identifyFeatureCollection(ctx, featureCollection)
 	if err != nil {
-		return featureCollection, nil, err
+		result.err = err
+		return result
 	}
 
-	return featureCollection, featureCollectionJSON, nil
+	if shouldCombine {
+		return result
+	}
+
+	result.json, result.err = marshalFeatureCollection(ctx, featureCollection)
+	return result
 }
 
-func pushMember(ctx context.Context, wg *sync.WaitGroup, ids chan<- int64, id int64) {
+func pushMember(ctx context.Context, wg *sync.WaitGroup, jobs chan<- job, j job) {
 	defer wg.Done()
 
 	log := ctxLog(ctx)
 	for {
-		if enqueueID(id, ids) {
-			log.Debugw("sub-area enqueued", "id", id)
+		if enqueueJob(j, jobs) {
+			log.Debugw("sub-area enqueued", "id", j.id)
 			break
 		}
 
@@ -208,105 +375,247 @@ func pushMember(ctx context.Context, wg *sync.WaitGroup, ids chan<- int64, id in
 	}
 }
 
-func reportResults(ctx context.Context, wg *sync.WaitGroup, results <-chan subArea) {
-	defer wg.Done()
-
+// reportResults writes outputs of sub-areas as they are handled, or the merged output once all of them are.
+// Failed writes of sub-areas are recorded as their outcomes. The error of the merged output is returned.
+func reportResults(ctx context.Context, results <-chan subArea, handled *[]subArea) error {
 	shouldCombine := ctxShouldCombine(ctx)
 	root, ok := ctxRoot(ctx)
 	if !ok || root == nil {
-		return
+		return errors.New("invalid root")
 	}
 
 	log := ctxLog(ctx)
+	parent := int64(root.ID)
 	featureCollection := geojson.FeatureCollection{
 		Type:     "FeatureCollection",
-		BBox:     geojson.BBox{},
 		Features: []*geojson.Feature{},
 	}
 
+	// workers finish in any order
+	features := []orderedFeature{}
+	failed := 0
 	for result := range results {
-		if !shouldCombine {
-			reportResult(ctx, result)
+		result.err = handleResult(ctx, parent, result)
+
+		// keeping the outcome only so the outputs can be garbage collected
+		*handled = append(*handled, subArea{
+			id:           result.id,
+			err:          result.err,
+			version:      result.version,
+			status:       result.status,
+			ways:         result.ways,
+			wayVersions:  result.wayVersions,
+			nodeVersions: result.nodeVersions,
+			unchanged:    result.unchanged,
+		})
+		if result.err != nil {
+			failed++
 			continue
 		}
 
-		if result.err != nil {
+		if !shouldCombine || result.fc == nil {
 			continue
 		}
 
-		if result.fc == nil {
-			continue
+		for _, feature := range result.fc.Features {
+			features = append(features, orderedFeature{id: result.id, feature: feature})
+		}
+	}
+
+	if !shouldCombine {
+		checkpointParent(ctx, parent, failed)
+		return nil
+	}
+
+	// the failure policy is checked along with the report
+	if tooManyFailures(ctx, failed) {
+		log.Errorw("merged output skipped", "failed", failed)
+		return nil
+	}
+
+	featureCollection.Features = sortFeatures(features, ctxSort(ctx))
+	if ctxIDFormat(ctx) != IDOSM {
+		// sub-areas are identified one by one so they may share IDs
+		duplicates := duplicateIDs(featureCollection.Features)
+		if len(duplicates) != 0 {
+			return fmt.Errorf("duplicate feature IDs of format %s in the merged output: %v", ctxIDFormat(ctx), duplicates)
 		}
+	}
 
-		featureCollection.Features = append(featureCollection.Features, result.fc.Features...)
+	boxes := make([]geojson.BBox, 0, len(featureCollection.Features))
+	for _, feature := range featureCollection.Features {
+		boxes = append(boxes, feature.BBox)
 	}
 
-	featureCollectionJSON, err := json.Marshal(featureCollection)
+	featureCollection.BBox = geoutil.UnionBBox(boxes...)
+
+	featureCollectionJSON, err := marshalFeatureCollection(ctx, &featureCollection)
 	if err != nil {
-		return
+		return fmt.Errorf("could not marshal the merged output: %w", err)
 	}
 
 	shouldPrint := ctxShouldPrint(ctx)
 	if shouldPrint {
 		fmt.Println(string(featureCollectionJSON))
+		return nil
+	}
+
+	version := ObjectVersion{ID: parent, Version: root.Version}
+	if unchangedOutput(ctx, version, *handled) {
+		log.Infow("unchanged output", "id", parent)
+		checkpointParent(ctx, parent, failed)
+		return nil
+	}
+
+	err = writeFile(ctx, parent, root.Tags.Find("name"), featureCollectionJSON, newMergedManifest(version, *handled, features))
+	if err != nil {
+		return err
+	}
+
+	checkpointParent(ctx, parent, failed)
+	return nil
+}
+
+// handleResult writes the output of a sub-area, or keeps it as a part of the merged output, then checkpoints it.
+// The outcome is returned, which is the error of the sub-area or of writing it.
+func handleResult(ctx context.Context, parent int64, result subArea) error {
+	if result.resumed {
+		return result.err
+	}
+
+	err := result.err
+	switch {
+	case err != nil:
+		ctxLog(ctx).Warnw("sub-area failed", "id", result.id, "error", err)
+	case ctxShouldCombine(ctx):
+		// parts are kept so a resumed run can merge them
+		err = ctxCheckpoint(ctx).writePart(parent, result.id, result.fc)
+	case result.unchanged:
+		ctxLog(ctx).Debugw("unchanged output", "id", result.id)
+	default:
+		err = reportResult(ctx, result)
+	}
+
+	checkpointResult(ctx, parent, result, err)
+	return err
+}
+
+// checkpointResult records the outcome of a sub-area which was handled by this run.
+func checkpointResult(ctx context.Context, parent int64, result subArea, err error) {
+	entry := CheckpointEntry{Parent: parent, ID: result.id, Version: result.version, Status: CheckpointDone, Result: result.status}
+	if err != nil {
+		entry = CheckpointEntry{Parent: parent, ID: result.id, Version: result.version, Status: CheckpointFailed, Error: err.Error()}
+	}
+
+	err = ctxCheckpoint(ctx).record(entry)
+	if err != nil {
+		ctxLog(ctx).Errorw("checkpoint failed", "error", err)
+	}
+}
+
+// checkpointParent records a parent as done once all of its sub-areas are written.
+func checkpointParent(ctx context.Context, parent int64, failed int) {
+	if failed != 0 {
+		return
+	}
+
+	root, ok := ctxRoot(ctx)
+	if !ok || root == nil {
 		return
 	}
 
-	err = writeFile(ctx, int64(root.ID), featureCollectionJSON)
+	checkpoint := ctxCheckpoint(ctx)
+	err := checkpoint.record(CheckpointEntry{Parent: parent, Version: root.Version, Status: CheckpointDone})
+	if err == nil {
+		err = checkpoint.removeParts(parent)
+	}
+
 	if err != nil {
-		log.Error(err)
+		ctxLog(ctx).Errorw("checkpoint failed", "error", err)
 	}
 }
 
-func reportResult(ctx context.Context, result subArea) {
+func summarize(ctx context.Context, parent int64, handled []subArea) {
 	log := ctxLog(ctx)
-	if result.err != nil {
-		log.Error(result.err)
-		return
+	counts := map[Completeness]int{}
+	failed := 0
+	for _, result := range handled {
+		if result.err != nil {
+			failed++
+			continue
+		}
+
+		counts[result.status]++
+		if result.status == CompletenessComplete {
+			continue
+		}
+
+		links := make([]string, 0, len(result.ways))
+		for _, way := range result.ways {
+			links = append(links, Link(osm.TypeWay, way))
+		}
+
+		log.Warnw(
+			"incomplete sub-area",
+			"id", result.id,
+			"status", result.status,
+			"url", Link(osm.TypeRelation, result.id),
+			"ways", result.ways,
+			"links", links,
+		)
 	}
 
+	log.Infow(
+		"sub-areas handled",
+		"parent", parent,
+		"total", len(handled),
+		"complete", counts[CompletenessComplete],
+		"partial", counts[CompletenessPartial],
+		"empty", counts[CompletenessEmpty],
+		"failed", failed,
+	)
+}
+
+func reportResult(ctx context.Context, result subArea) error {
+	log := ctxLog(ctx)
 	shouldPrint := ctxShouldPrint(ctx)
 	if shouldPrint {
 		fmt.Println(string(result.json))
-		return
+		return nil
 	}
 
-	err := writeFile(ctx, result.id, result.json)
+	err := writeFile(ctx, result.id, result.name, result.json, &Manifest{SubAreas: []SubAreaManifest{newSubAreaManifest(result)}})
 	if err != nil {
 		log.Error(err)
 	}
+
+	return err
 }
 
-func writeFile(ctx context.Context, id int64, data []byte) error {
+func writeFile(ctx context.Context, id int64, name string, data []byte, manifest *Manifest) error {
 	log := ctxLog(ctx)
-	path, ok := filePath(ctx, id)
+	path, ok := filePath(ctx, id, name)
 	if !ok {
 		return errors.New("invalid directory")
 	}
 
-	log.Infow("writing", "path", path)
-	return ioutil.WriteFile(path, data, 0o644)
-}
-
-func filePath(ctx context.Context, id int64) (string, bool) {
-	dir, ok := ctxOutDir(ctx)
-	if !ok {
-		return "", false
+	// templates may have sub-directories
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
 	}
 
-	shouldRewind := ctxShouldRewind(ctx)
-	if shouldRewind {
-		name := fmt.Sprintf("%d-rewind.geojson", id)
-		return filepath.Join(dir, filepath.Base(name)), true
+	if manifest != nil {
+		manifest.Options = optionsHash(ctx)
 	}
 
-	name := fmt.Sprintf("%d.geojson", id)
-	return filepath.Join(dir, filepath.Base(name)), true
+	log.Infow("writing", "path", path)
+	return writeOutput(path, data, ctxCompression(ctx), manifest)
 }
 
-func enqueueID(id int64, ids chan<- int64) bool {
+func enqueueJob(j job, jobs chan<- job) bool {
 	select {
-	case ids <- id:
+	case jobs <- j:
 		return true
 	default:
 		return false
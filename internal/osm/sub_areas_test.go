@@ -0,0 +1,108 @@
+package osm
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+)
+
+func newTestResults(results ...subArea) <-chan subArea {
+	c := make(chan subArea, len(results))
+	for _, result := range results {
+		c <- result
+	}
+
+	close(c)
+	return c
+}
+
+func testSubAreas() []subArea {
+	return []subArea{
+		{
+			id:     12,
+			fc:     newTestCollection(newTestFeature("relation/12", map[string]string{"name": "Huế"})),
+			json:   []byte(`{"type":"FeatureCollection","features":[]}`),
+			status: CompletenessComplete,
+		},
+		{
+			id:     11,
+			fc:     newTestCollection(newTestFeature("relation/11", map[string]string{"name": "Hà Nội"})),
+			json:   []byte(`{"type":"FeatureCollection","features":[]}`),
+			status: CompletenessComplete,
+		},
+		{id: 13, err: errors.New("timeout")},
+	}
+}
+
+func TestReportResultsSeparated(t *testing.T) {
+	is := is.New(t)
+
+	parent := &osm.Relation{ID: 1, Version: 3}
+	dir := testDir(t)
+	handled := []subArea{}
+	err := reportResults(newTestContext(&testLogger{}, dir, true, parent), newTestResults(testSubAreas()...), &handled)
+	is.NoErr(err)
+	is.Equal(len(handled), 3)
+	is.Equal(handled[2].err.Error(), "timeout")
+
+	for _, name := range []string{"11.geojson", "12.geojson", "12.geojson.sum", "12.geojson.manifest"} {
+		_, err = os.Stat(filepath.Join(dir, name))
+		is.NoErr(err) // output written
+	}
+
+	// outputs which can't be written fail their sub-areas
+	blocked := filepath.Join(dir, "blocked")
+	is.NoErr(ioutil.WriteFile(blocked, nil, 0644))
+
+	handled = []subArea{}
+	err = reportResults(newTestContext(&testLogger{}, filepath.Join(blocked, "out"), true, parent), newTestResults(testSubAreas()...), &handled)
+	is.NoErr(err)
+	is.Equal(len(handled), 3)
+	for _, result := range handled {
+		is.True(result.err != nil) // write failed
+	}
+}
+
+func TestReportResultsMerged(t *testing.T) {
+	is := is.New(t)
+
+	parent := &osm.Relation{ID: 1, Version: 3, Tags: osm.Tags{{Key: "name", Value: "Việt Nam"}}}
+	dir := testDir(t)
+	handled := []subArea{}
+	err := reportResults(newTestContext(&testLogger{}, dir, false, parent), newTestResults(testSubAreas()...), &handled)
+	is.NoErr(err)
+	is.Equal(len(handled), 3)
+
+	fc, err := ReadFeatureCollection(filepath.Join(dir, "1.geojson"))
+	is.NoErr(err)
+	is.Equal(len(fc.Features), 2)
+	is.Equal(fc.Features[0].ID, "relation/11")
+	is.Equal(fc.Features[1].ID, "relation/12")
+
+	// the merged output which can't be written fails the parent
+	blocked := filepath.Join(dir, "blocked")
+	is.NoErr(ioutil.WriteFile(blocked, nil, 0644))
+
+	handled = []subArea{}
+	err = reportResults(newTestContext(&testLogger{}, filepath.Join(blocked, "out"), false, parent), newTestResults(testSubAreas()...), &handled)
+	is.True(err != nil)
+
+	// sub-areas sharing a promoted ID fail the merged output, features are identified by the workers
+	shared := testSubAreas()
+	for _, result := range shared[:2] {
+		result.fc.Features[0].ID = "VN-HN"
+	}
+
+	dir = testDir(t)
+	handled = []subArea{}
+	ctx := CtxSetIDFormat(newTestContext(&testLogger{}, dir, false, parent), "property:ISO3166-2")
+	err = reportResults(ctx, newTestResults(shared...), &handled)
+	is.True(err != nil)
+	_, err = os.Stat(filepath.Join(dir, "1.geojson"))
+	is.True(os.IsNotExist(err))
+}
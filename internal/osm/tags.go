@@ -0,0 +1,181 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+var constTags = []string{"name", "type"} // slice isn't immutable by nature
+
+// TagFilter decides which OpenStreetMap tags are matched.
+// Patterns follow path.Match, e.g. "name:*".
+type TagFilter struct {
+	all      bool
+	patterns []string
+}
+
+// NewTagFilter constructs a TagFilter. All tags are matched if all is true.
+func NewTagFilter(patterns []string, all bool) (TagFilter, error) {
+	for _, pattern := range patterns {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return TagFilter{}, fmt.Errorf("invalid tag pattern: %s", pattern)
+		}
+	}
+
+	return TagFilter{all: all, patterns: patterns}, nil
+}
+
+// Match determines if a tag key is matched by the filter.
+func (filter TagFilter) Match(key string) bool {
+	if filter.all {
+		return true
+	}
+
+	for _, pattern := range filter.patterns {
+		matched, _ := path.Match(pattern, key)
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Empty determines if the filter has no pattern.
+func (filter TagFilter) Empty() bool {
+	return !filter.all && len(filter.patterns) == 0
+}
+
+// filterTags keeps whitelisted tags and normalizes them one by one.
+// Normalized values replace the original ones which are kept under "<key>:original".
+func filterTags(ctx context.Context, tags osm.Tags) osm.Tags {
+	shouldNormalize := ctxShouldNormalize(ctx)
+	whitelist := ctxTags(ctx)
+	normalizing := ctxNormalizedTags(ctx)
+	normalizer := ctxNormalizer(ctx)
+
+	// tags required by other options are kept regardless of the whitelist
+	// osmgeojson builds no feature of a relation without its type
+	required := map[string]bool{"type": true}
+	langs := ctxLanguages(ctx)
+	if len(langs) != 0 {
+		tags = localizeTags(tags, langs)
+		required["name"] = true
+		for _, lang := range langs {
+			required[localizedKey(lang)] = true
+		}
+	}
+
+	mapping, ok := ctxMapping(ctx)
+	if ok {
+		for _, key := range mapping.keys() {
+			required[key] = true
+		}
+	}
+
+	// a promoted tag would silently fall back to the IDs of osmgeojson
+	property, ok := ctxIDFormat(ctx).property()
+	if ok {
+		required[property] = true
+	}
+
+	// merged sub-areas would silently keep the order of their IDs
+	sortKey := ctxSort(ctx)
+	if sortKey != "" {
+		required[sortKey] = true
+	}
+
+	result := osm.Tags{}
+	for _, tag := range tags {
+		if !whitelist.Match(tag.Key) && !required[tag.Key] {
+			continue
+		}
+
+		if !shouldNormalize || !normalizing.Match(tag.Key) {
+			result = append(result, tag)
+			continue
+		}
+
+		newTag := tag
+		newTag.Value = normalizer(tag.Value, tagLanguage(tag.Key))
+		if tag.Value == newTag.Value {
+			result = append(result, tag)
+			continue
+		}
+
+		tag.Key = fmt.Sprintf("%s:original", tag.Key)
+		result = append(result, tag, newTag)
+	}
+
+	return result
+}
+
+// localizeTags resolves "name" through the fallback chain "name:<lang>"..., "int_name", "name".
+// A "name_<lang>" tag is added for each language, falling back to "name".
+func localizeTags(tags osm.Tags, langs []string) osm.Tags {
+	local := tags.Find("name")
+	name := ""
+	for _, lang := range langs {
+		name = tags.Find(fmt.Sprintf("name:%s", lang))
+		if name != "" {
+			break
+		}
+	}
+
+	if name == "" {
+		name = tags.Find("int_name")
+	}
+
+	if name == "" {
+		name = local
+	}
+
+	result := make(osm.Tags, 0, len(tags)+len(langs))
+	for _, tag := range tags {
+		if tag.Key == "name" {
+			continue
+		}
+
+		result = append(result, tag)
+	}
+
+	if name != "" {
+		result = append(result, osm.Tag{Key: "name", Value: name})
+	}
+
+	for _, lang := range langs {
+		value := tags.Find(fmt.Sprintf("name:%s", lang))
+		if value == "" {
+			value = local
+		}
+
+		if value == "" {
+			continue
+		}
+
+		result = append(result, osm.Tag{Key: localizedKey(lang), Value: value})
+	}
+
+	return result
+}
+
+// tagLanguage returns the language suffix of a tag key, e.g. "vi" of "name:vi" or "name_vi".
+// Unknown languages are ignored by normalizers so suffixes like "addr:city" are harmless.
+func tagLanguage(key string) string {
+	i := strings.LastIndexAny(key, ":_")
+	if i < 0 {
+		return ""
+	}
+
+	return key[i+1:]
+}
+
+// localizedKey returns the key of a localized name, e.g. "name_vi".
+func localizedKey(lang string) string {
+	return fmt.Sprintf("name_%s", lang)
+}
@@ -0,0 +1,191 @@
+package osm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hiendv/geojson/pkg/util"
+	"github.com/matryer/is"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmgeojson"
+)
+
+func TestNewTagFilter(t *testing.T) {
+	is := is.New(t)
+
+	filter, err := NewTagFilter([]string{"name:*", "ISO3166-2"}, false)
+	is.NoErr(err)
+	is.True(filter.Match("name:vi"))
+	is.True(filter.Match("ISO3166-2"))
+	is.True(!filter.Match("name"))
+	is.True(!filter.Empty())
+
+	filter, err = NewTagFilter(nil, true)
+	is.NoErr(err)
+	is.True(filter.Match("anything"))
+	is.True(!filter.Empty())
+
+	filter, err = NewTagFilter(nil, false)
+	is.NoErr(err)
+	is.True(filter.Empty())
+
+	_, err = NewTagFilter([]string{"[name"}, false)
+	is.True(err != nil)
+}
+
+func TestFilterTags(t *testing.T) {
+	is := is.New(t)
+
+	tags := osm.Tags{
+		{Key: "name", Value: "Đà Nẵng"},
+		{Key: "name:en", Value: "Da Nang"},
+		{Key: "type", Value: "boundary"},
+		{Key: "admin_level", Value: "4"},
+	}
+
+	filter := func(patterns []string, all bool) TagFilter {
+		f, err := NewTagFilter(patterns, all)
+		is.NoErr(err)
+		return f
+	}
+
+	ascii, err := CtxSetNormalizer(CtxSetNormalizedTags(CtxSetTags(context.Background(), filter(nil, true)), filter([]string{"name"}, false)), util.NormalizerASCII)
+	is.NoErr(err)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want osm.Tags
+	}{
+		{
+			"default whitelist",
+			context.Background(),
+			osm.Tags{{Key: "name:original", Value: "Đà Nẵng"}, {Key: "name", Value: "Đa Nang"}, {Key: "type", Value: "boundary"}},
+		},
+		{
+			"raw",
+			context.WithValue(context.Background(), ctxKeyRaw, true),
+			osm.Tags{{Key: "name", Value: "Đà Nẵng"}, {Key: "type", Value: "boundary"}},
+		},
+		{
+			"whitelist",
+			CtxSetTags(context.Background(), filter([]string{"name:*", "admin_level"}, false)),
+			osm.Tags{{Key: "name:en", Value: "Da Nang"}, {Key: "type", Value: "boundary"}, {Key: "admin_level", Value: "4"}},
+		},
+		{
+			"normalized tags and normalizer",
+			ascii,
+			osm.Tags{
+				{Key: "name:original", Value: "Đà Nẵng"},
+				{Key: "name", Value: "Da Nang"},
+				{Key: "name:en", Value: "Da Nang"},
+				{Key: "type", Value: "boundary"},
+				{Key: "admin_level", Value: "4"},
+			},
+		},
+		{
+			"tag promoted to IDs",
+			CtxSetIDFormat(CtxSetTags(context.WithValue(context.Background(), ctxKeyRaw, true), filter([]string{"name"}, false)), "property:admin_level"),
+			osm.Tags{{Key: "name", Value: "Đà Nẵng"}, {Key: "type", Value: "boundary"}, {Key: "admin_level", Value: "4"}},
+		},
+		{
+			"tags of the mapping",
+			CtxSetMapping(context.WithValue(context.Background(), ctxKeyRaw, true), &Mapping{Types: map[string]PropertyType{"admin_level": TypeInteger}}),
+			osm.Tags{{Key: "name", Value: "Đà Nẵng"}, {Key: "type", Value: "boundary"}, {Key: "admin_level", Value: "4"}},
+		},
+	}
+
+	for _, test := range tests {
+		is.Equal(filterTags(test.ctx, tags), test.want) // test.name
+	}
+}
+
+func TestFilterTagsConvert(t *testing.T) {
+	is := is.New(t)
+
+	filter, err := NewTagFilter([]string{"name", "admin_level"}, false)
+	is.NoErr(err)
+	ctx := CtxSetTags(context.WithValue(context.Background(), ctxKeyRaw, true), filter)
+
+	relation := &osm.Relation{
+		ID:      1,
+		Members: osm.Members{{Type: osm.TypeWay, Ref: 10, Role: "outer"}},
+		Tags:    osm.Tags{{Key: "name", Value: "Đà Nẵng"}, {Key: "type", Value: "boundary"}, {Key: "boundary", Value: "administrative"}},
+	}
+	relation.Tags = filterTags(ctx, relation.Tags)
+
+	o := &osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 1, Lat: 16, Lon: 108, Visible: true},
+			{ID: 2, Lat: 16, Lon: 108.1, Visible: true},
+			{ID: 3, Lat: 16.1, Lon: 108.1, Visible: true},
+		},
+		Ways:      osm.Ways{{ID: 10, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 1}}, Visible: true}},
+		Relations: osm.Relations{relation},
+	}
+
+	fc, err := osmgeojson.Convert(o)
+	is.NoErr(err)
+
+	ids := []interface{}{}
+	for _, feature := range fc.Features {
+		ids = append(ids, feature.ID)
+	}
+	is.Equal(ids, []interface{}{"relation/1"}) // the whitelisted relation is still a boundary
+}
+
+func TestLocalizeTags(t *testing.T) {
+	is := is.New(t)
+
+	tags := osm.Tags{
+		{Key: "name", Value: "Hà Nội"},
+		{Key: "name:en", Value: "Hanoi"},
+		{Key: "int_name", Value: "Ha Noi"},
+	}
+
+	tests := []struct {
+		langs []string
+		want  osm.Tags
+	}{
+		{
+			[]string{"fr", "en"},
+			osm.Tags{
+				{Key: "name:en", Value: "Hanoi"},
+				{Key: "int_name", Value: "Ha Noi"},
+				{Key: "name", Value: "Hanoi"},
+				{Key: "name_fr", Value: "Hà Nội"},
+				{Key: "name_en", Value: "Hanoi"},
+			},
+		},
+		{
+			// int_name comes before the local name
+			[]string{"ja"},
+			osm.Tags{
+				{Key: "name:en", Value: "Hanoi"},
+				{Key: "int_name", Value: "Ha Noi"},
+				{Key: "name", Value: "Ha Noi"},
+				{Key: "name_ja", Value: "Hà Nội"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		is.Equal(localizeTags(tags, test.langs), test.want) // test.langs
+	}
+
+	// nothing to fall back to
+	is.Equal(localizeTags(osm.Tags{{Key: "type", Value: "boundary"}}, []string{"vi"}), osm.Tags{{Key: "type", Value: "boundary"}})
+
+	// localized names are kept regardless of the whitelist
+	ctx := CtxSetLanguages(context.WithValue(context.Background(), ctxKeyRaw, true), []string{"en"})
+	is.Equal(filterTags(ctx, tags), osm.Tags{{Key: "name", Value: "Hanoi"}, {Key: "name_en", Value: "Hanoi"}})
+}
+
+func TestTagLanguage(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(tagLanguage("name:vi"), "vi")
+	is.Equal(tagLanguage("name_en"), "en")
+	is.Equal(tagLanguage("name"), "")
+	is.Equal(localizedKey("vi"), "name_vi")
+}
@@ -0,0 +1,143 @@
+package osm
+
+import (
+	"context"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmapi"
+)
+
+// waitUpstream blocks until the upstream rate limit allows another request.
+func waitUpstream(ctx context.Context) error {
+	limiter := ctxUpstreamLimiter(ctx)
+	if limiter == nil {
+		return nil
+	}
+
+	return limiter.Wait(ctx)
+}
+
+// fetchParent queries a parent relation, at the version of the snapshot if any.
+func fetchParent(ctx context.Context, id int64) (*osm.Relation, error) {
+	version, ok := ctxSnapshotVersion(ctx)
+	if ok {
+		return fetchRelationVersion(ctx, id, version)
+	}
+
+	return fetchRelation(ctx, id)
+}
+
+// fetchRelation queries a relation without its members, as it was at the time of the snapshot if any.
+func fetchRelation(ctx context.Context, id int64) (*osm.Relation, error) {
+	at, ok := ctxSnapshot(ctx)
+	if ok {
+		return fetchRelationAt(ctx, id, at)
+	}
+
+	err := waitUpstream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return osmapi.Relation(ctx, osm.RelationID(id))
+}
+
+// fetchRelationFull queries a relation along with its members, as they were at the time of the snapshot if any, through the response cache if any.
+func fetchRelationFull(ctx context.Context, id int64) (*osm.OSM, error) {
+	cache := ctxResponseCache(ctx)
+	at, ok := ctxSnapshot(ctx)
+	if ok {
+		if cache != nil {
+			return cache.relationFullAt(ctx, id, at)
+		}
+
+		return fetchRelationFullAtUncached(ctx, id, at)
+	}
+
+	if cache != nil {
+		return cache.relationFull(ctx, id)
+	}
+
+	return fetchRelationFullUncached(ctx, id)
+}
+
+func fetchRelationFullUncached(ctx context.Context, id int64) (*osm.OSM, error) {
+	err := waitUpstream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return osmapi.RelationFull(ctx, osm.RelationID(id))
+}
+
+// constObjectChunk is the number of objects queried at once, which keeps URLs short enough.
+const constObjectChunk = 200
+
+// chunkIDs splits IDs into chunks of constObjectChunk.
+func chunkIDs(ids []int64) [][]int64 {
+	chunks := [][]int64{}
+	for start := 0; start < len(ids); start += constObjectChunk {
+		end := start + constObjectChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		chunks = append(chunks, ids[start:end])
+	}
+
+	return chunks
+}
+
+// fetchWays queries the current versions of ways at once, by chunks. Deleted ways are returned as invisible.
+func fetchWays(ctx context.Context, ids []int64) (map[int64]*osm.Way, error) {
+	result := map[int64]*osm.Way{}
+	for _, ids := range chunkIDs(ids) {
+		chunk := make([]osm.WayID, 0, len(ids))
+		for _, id := range ids {
+			chunk = append(chunk, osm.WayID(id))
+		}
+
+		err := waitUpstream(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ways, err := osmapi.Ways(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, way := range ways {
+			result[int64(way.ID)] = way
+		}
+	}
+
+	return result, nil
+}
+
+// fetchNodes queries the current versions of nodes at once, by chunks. Deleted nodes are returned as invisible.
+func fetchNodes(ctx context.Context, ids []int64) (map[int64]*osm.Node, error) {
+	result := map[int64]*osm.Node{}
+	for _, ids := range chunkIDs(ids) {
+		chunk := make([]osm.NodeID, 0, len(ids))
+		for _, id := range ids {
+			chunk = append(chunk, osm.NodeID(id))
+		}
+
+		err := waitUpstream(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes, err := osmapi.Nodes(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range nodes {
+			result[int64(node.ID)] = node
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiendv/geojson/pkg/geoutil"
+	"github.com/paulmach/orb/geojson"
+)
+
+// ValidationMode specifies how invalid geometries are handled.
+type ValidationMode string
+
+const (
+	// ValidationNone skips the validation.
+	ValidationNone ValidationMode = ""
+	// ValidationWarn logs issues of geometries.
+	ValidationWarn ValidationMode = "warn"
+	// ValidationFix repairs geometries and rejects sub-areas which are still invalid, e.g. holes crossing their outer rings.
+	ValidationFix ValidationMode = "fix"
+	// ValidationFail rejects sub-areas with invalid geometries.
+	ValidationFail ValidationMode = "fail"
+)
+
+// ParseValidationMode interprets a string as a ValidationMode.
+func ParseValidationMode(str string) (ValidationMode, error) {
+	switch mode := ValidationMode(str); mode {
+	case ValidationNone, ValidationWarn, ValidationFix, ValidationFail:
+		return mode, nil
+	default:
+		return ValidationNone, fmt.Errorf("invalid validation mode: %s", str)
+	}
+}
+
+func validateFeatureCollection(ctx context.Context, fc *geojson.FeatureCollection, mode ValidationMode) error {
+	log := ctxLog(ctx)
+	for _, feature := range fc.Features {
+		issues, err := geoutil.ValidateFeature(feature)
+		if err != nil {
+			return err
+		}
+
+		if len(issues) == 0 {
+			continue
+		}
+
+		if mode != ValidationFix {
+			log.Warnw("invalid geometry", "id", feature.ID, "issues", issues)
+		}
+
+		if mode == ValidationFail {
+			return fmt.Errorf("invalid geometry of %v: %d issue(s)", feature.ID, len(issues))
+		}
+
+		if mode != ValidationFix {
+			continue
+		}
+
+		err = geoutil.RepairFeature(feature)
+		if err != nil {
+			return fmt.Errorf("could not repair geometry of %v: %w", feature.ID, err)
+		}
+
+		remaining, err := geoutil.ValidateFeature(feature)
+		if err != nil {
+			return err
+		}
+
+		if len(remaining) != 0 {
+			log.Warnw("invalid geometry", "id", feature.ID, "issues", remaining)
+			return fmt.Errorf("invalid geometry of %v after repair: %d issue(s)", feature.ID, len(remaining))
+		}
+
+		log.Infow("geometry repaired", "id", feature.ID, "issues", issues)
+	}
+
+	return nil
+}
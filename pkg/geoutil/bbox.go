@@ -0,0 +1,147 @@
+package geoutil
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// BBoxFeatureCollection computes RFC 7946 bounding boxes of a GeoJSON feature collection and its features.
+func BBoxFeatureCollection(fc *geojson.FeatureCollection) error {
+	if fc == nil {
+		return errors.New("invalid feature collection")
+	}
+
+	boxes := make([]geojson.BBox, 0, len(fc.Features))
+	for _, feature := range fc.Features {
+		err := BBoxFeature(feature)
+		if err != nil {
+			return err
+		}
+
+		boxes = append(boxes, feature.BBox)
+	}
+
+	fc.BBox = UnionBBox(boxes...)
+	return nil
+}
+
+// BBoxFeature computes the RFC 7946 bounding box of a GeoJSON feature.
+func BBoxFeature(f *geojson.Feature) error {
+	if f == nil {
+		return errors.New("invalid feature")
+	}
+
+	bbox, err := BBox(f.Geometry)
+	if err != nil {
+		return err
+	}
+
+	f.BBox = bbox
+	return nil
+}
+
+// BBox computes the RFC 7946 bounding box of a GeoJSON geometry.
+// Polygons of a MultiPolygon on both sides of the antimeridian result in a box whose west is greater than its east.
+func BBox(g orb.Geometry) (geojson.BBox, error) {
+	if g == nil {
+		return nil, errors.New("invalid geometry")
+	}
+
+	if g.GeoJSONType() == geometryPolygon {
+		p, ok := g.(orb.Polygon)
+		if !ok {
+			return nil, errors.New("invalid Polygon")
+		}
+
+		return UnionBBox(polygonBBox(p)), nil
+	}
+
+	if g.GeoJSONType() == geometryMultiPolygon {
+		mp, ok := g.(orb.MultiPolygon)
+		if !ok {
+			return nil, errors.New("invalid MultiPolygon")
+		}
+
+		boxes := make([]geojson.BBox, 0, len(mp))
+		for _, p := range mp {
+			boxes = append(boxes, polygonBBox(p))
+		}
+
+		return UnionBBox(boxes...), nil
+	}
+
+	return nil, errors.New("geometry type not supported")
+}
+
+// UnionBBox computes the smallest RFC 7946 bounding box covering the given ones.
+// The longitude span is the complement of the largest gap around the globe so boxes may cross the antimeridian.
+func UnionBBox(boxes ...geojson.BBox) geojson.BBox {
+	intervals := [][2]float64{}
+	south, north := 90.0, -90.0
+	for _, box := range boxes {
+		if len(box) != 4 {
+			continue
+		}
+
+		west, east := box[0], box[2]
+		if west <= east {
+			intervals = append(intervals, [2]float64{west, east})
+		} else {
+			intervals = append(intervals, [2]float64{west, 180}, [2]float64{-180, east})
+		}
+
+		if box[1] < south {
+			south = box[1]
+		}
+
+		if box[3] > north {
+			north = box[3]
+		}
+	}
+
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i][0] < intervals[j][0]
+	})
+
+	merged := [][2]float64{intervals[0]}
+	for _, interval := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if interval[0] > last[1] {
+			merged = append(merged, interval)
+			continue
+		}
+
+		if interval[1] > last[1] {
+			last[1] = interval[1]
+		}
+	}
+
+	// the gap across the antimeridian comes first so a box crossing it is only chosen if it's strictly smaller
+	west, east := merged[0][0], merged[len(merged)-1][1]
+	gap := merged[0][0] + 360 - merged[len(merged)-1][1]
+	for i := 1; i < len(merged); i++ {
+		if merged[i][0]-merged[i-1][1] > gap {
+			gap = merged[i][0] - merged[i-1][1]
+			west, east = merged[i][0], merged[i-1][1]
+		}
+	}
+
+	return geojson.BBox{west, south, east, north}
+}
+
+func polygonBBox(p orb.Polygon) geojson.BBox {
+	if len(p) == 0 || len(p[0]) == 0 {
+		return nil
+	}
+
+	// holes are within the outer ring
+	bound := p[0].Bound()
+	return geojson.BBox{bound.Min[0], bound.Min[1], bound.Max[0], bound.Max[1]}
+}
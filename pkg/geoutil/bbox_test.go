@@ -0,0 +1,89 @@
+package geoutil
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestUnionBBox(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(UnionBBox(), geojson.BBox(nil))
+	is.Equal(UnionBBox(nil, geojson.BBox{1, 2}), geojson.BBox(nil))
+	is.Equal(UnionBBox(
+		geojson.BBox{1, 1, 2, 2},
+		geojson.BBox{-3, 0, -2, 1},
+	), geojson.BBox{-3, 0, 2, 2})
+
+	// Fiji-like: both sides of the antimeridian
+	is.Equal(UnionBBox(
+		geojson.BBox{177, -19, 180, -16},
+		geojson.BBox{-180, -18, -178, -15},
+	), geojson.BBox{177, -19, -178, -15})
+
+	// a box which already crosses the antimeridian
+	is.Equal(UnionBBox(
+		geojson.BBox{170, 0, -170, 1},
+		geojson.BBox{-175, -1, -160, 0},
+	), geojson.BBox{170, -1, -160, 1})
+}
+
+func TestBBoxInvalid(t *testing.T) {
+	is := is.New(t)
+
+	_, err := BBox(nil)
+	is.True(err != nil)
+
+	_, err = BBox(&invalidPolygon{})
+	is.True(err != nil)
+
+	_, err = BBox(&invalidMultiPolygon{})
+	is.True(err != nil)
+
+	_, err = BBox(orb.MultiPoint(nil))
+	is.True(err != nil)
+
+	err = BBoxFeature(nil)
+	is.True(err != nil)
+
+	err = BBoxFeatureCollection(nil)
+	is.True(err != nil)
+}
+
+func TestBBoxFeatureCollection(t *testing.T) {
+	is := is.New(t)
+
+	fc := geojson.NewFeatureCollection()
+	fc.Append(geojson.NewFeature(orb.Polygon{{
+		orb.Point{1, 1},
+		orb.Point{1, 2},
+		orb.Point{2, 2},
+		orb.Point{2, 1},
+		orb.Point{1, 1},
+	}}))
+	fc.Append(geojson.NewFeature(orb.MultiPolygon{
+		{{
+			orb.Point{179, 1},
+			orb.Point{179, 3},
+			orb.Point{180, 3},
+			orb.Point{180, 1},
+			orb.Point{179, 1},
+		}},
+		{{
+			orb.Point{-180, 0},
+			orb.Point{-180, 1},
+			orb.Point{-179, 1},
+			orb.Point{-179, 0},
+			orb.Point{-180, 0},
+		}},
+	}))
+
+	err := BBoxFeatureCollection(fc)
+	is.NoErr(err)
+	is.Equal(fc.Features[0].BBox, geojson.BBox{1, 1, 2, 2})
+	is.Equal(fc.Features[1].BBox, geojson.BBox{179, 0, -179, 3})
+	is.Equal(fc.BBox, geojson.BBox{1, 0, -179, 3})
+}
@@ -0,0 +1,149 @@
+package geoutil
+
+import (
+	"errors"
+	"math"
+
+	"github.com/paulmach/orb"
+)
+
+// HausdorffDistance computes the Hausdorff distance between the boundaries of two GeoJSON geometries in meters,
+// which is the farthest any vertex of one geometry is from the other geometry.
+// Distances to edges are measured on a local equirectangular projection, which is accurate for the small distances of boundary edits.
+// Credit: Taha & Hanbury, An Efficient Algorithm for Calculating the Exact Hausdorff Distance (2015) for the early break.
+func HausdorffDistance(a, b orb.Geometry) (float64, error) {
+	linesA, err := geometryLines(a)
+	if err != nil {
+		return 0, err
+	}
+
+	linesB, err := geometryLines(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Max(directedHausdorff(linesA, linesB), directedHausdorff(linesB, linesA)), nil
+}
+
+// geometryLines flattens a geometry into lines. Points are lines of a single point.
+func geometryLines(g orb.Geometry) ([]orb.LineString, error) {
+	switch g := g.(type) {
+	case nil:
+		return nil, errors.New("invalid geometry")
+	case orb.Point:
+		return []orb.LineString{{g}}, nil
+	case orb.MultiPoint:
+		lines := make([]orb.LineString, 0, len(g))
+		for _, p := range g {
+			lines = append(lines, orb.LineString{p})
+		}
+
+		return lines, nil
+	case orb.LineString:
+		return []orb.LineString{g}, nil
+	case orb.MultiLineString:
+		return g, nil
+	case orb.Ring:
+		return []orb.LineString{orb.LineString(g)}, nil
+	case orb.Polygon:
+		lines := make([]orb.LineString, 0, len(g))
+		for _, ring := range g {
+			lines = append(lines, orb.LineString(ring))
+		}
+
+		return lines, nil
+	case orb.MultiPolygon:
+		lines := []orb.LineString{}
+		for _, p := range g {
+			for _, ring := range p {
+				lines = append(lines, orb.LineString(ring))
+			}
+		}
+
+		return lines, nil
+	case orb.Collection:
+		lines := []orb.LineString{}
+		for _, child := range g {
+			childLines, err := geometryLines(child)
+			if err != nil {
+				return nil, err
+			}
+
+			lines = append(lines, childLines...)
+		}
+
+		return lines, nil
+	}
+
+	return nil, errors.New("geometry type not supported")
+}
+
+// directedHausdorff computes the farthest distance from vertices of a to the lines of b.
+// The scan over b stops as soon as a vertex is closer than the current maximum, since it can't raise the maximum anymore.
+func directedHausdorff(a, b []orb.LineString) float64 {
+	max := 0.0
+	for _, line := range a {
+		for _, p := range line {
+			min := math.Inf(1)
+			for _, other := range b {
+				d := lineDistance(p, other, max)
+				if d < min {
+					min = d
+				}
+
+				if min <= max {
+					break
+				}
+			}
+
+			if !math.IsInf(min, 1) && min > max {
+				max = min
+			}
+		}
+	}
+
+	return max
+}
+
+// lineDistance computes the distance from a point to a line in meters.
+// It stops early once the distance is below the threshold.
+func lineDistance(p orb.Point, line orb.LineString, threshold float64) float64 {
+	if len(line) == 1 {
+		return GeodesicDistance(p, line[0])
+	}
+
+	min := math.Inf(1)
+	for i := 1; i < len(line); i++ {
+		d := segmentDistance(p, line[i-1], line[i])
+		if d < min {
+			min = d
+		}
+
+		if min <= threshold {
+			break
+		}
+	}
+
+	return min
+}
+
+// segmentDistance computes the distance from a point to a segment on the equirectangular projection centered at the point.
+func segmentDistance(p, s1, s2 orb.Point) float64 {
+	scale := deg2rad(1) * wgs84RQ
+	cosLat := math.Cos(deg2rad(p[1]))
+	project := func(q orb.Point) (float64, float64) {
+		return math.Remainder(q[0]-p[0], 360) * cosLat * scale, (q[1] - p[1]) * scale
+	}
+
+	x1, y1 := project(s1)
+	x2, y2 := project(s2)
+	dx, dy := x2-x1, y2-y1
+
+	t := 0.0
+	if dx != 0 || dy != 0 {
+		t = -(x1*dx + y1*dy) / (dx*dx + dy*dy)
+		t = math.Max(0, math.Min(1, t))
+	}
+
+	return math.Hypot(x1+t*dx, y1+t*dy)
+}
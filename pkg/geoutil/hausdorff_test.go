@@ -0,0 +1,46 @@
+package geoutil
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+)
+
+func TestHausdorffDistance(t *testing.T) {
+	is := is.New(t)
+
+	_, err := HausdorffDistance(nil, orb.Point{0, 0})
+	is.True(err != nil)
+
+	_, err = HausdorffDistance(orb.Point{0, 0}, orb.Bound{})
+	is.True(err != nil)
+
+	cell := orb.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}
+	d, err := HausdorffDistance(cell, cell)
+	is.NoErr(err)
+	is.Equal(d, 0.0)
+
+	// moving the northern edge by 0.01 degrees of latitude
+	moved := orb.Polygon{{{0, 0}, {1, 0}, {1, 1.01}, {0, 1.01}, {0, 0}}}
+	d, err = HausdorffDistance(cell, moved)
+	is.NoErr(err)
+	is.True(almostEqual(d, 0.01*deg2rad(1)*wgs84RQ, 1e-9))
+
+	// a vertex added in the middle of an edge changes nothing
+	split := orb.Polygon{{{0, 0}, {0.5, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}
+	d, err = HausdorffDistance(cell, split)
+	is.NoErr(err)
+	is.Equal(d, 0.0)
+
+	// points are compared as they are
+	d, err = HausdorffDistance(orb.Point{0, 0}, orb.MultiPoint{{0, 0}, {1, 0}})
+	is.NoErr(err)
+	is.True(almostEqual(d, 111319.491, 1e-8))
+
+	// a hole of a MultiPolygon
+	holed := orb.MultiPolygon{{cell[0], {{0.4, 0.4}, {0.6, 0.4}, {0.6, 0.6}, {0.4, 0.6}, {0.4, 0.4}}}}
+	d, err = HausdorffDistance(orb.MultiPolygon{cell}, holed)
+	is.NoErr(err)
+	is.True(d > 0)
+}
@@ -0,0 +1,179 @@
+package geoutil
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+// constLabelPrecision is the precision of LabelPoint relative to the size of the polygon.
+const constLabelPrecision = 1e-4
+
+// Centroid computes the area-weighted centroid of a GeoJSON geometry.
+// Holes are subtracted. The centroid of a concave polygon may fall outside of it.
+func Centroid(g orb.Geometry) (orb.Point, error) {
+	if g == nil {
+		return orb.Point{}, errors.New("invalid geometry")
+	}
+
+	if g.GeoJSONType() != geometryPolygon && g.GeoJSONType() != geometryMultiPolygon {
+		return orb.Point{}, errors.New("geometry type not supported")
+	}
+
+	centroid, area := planar.CentroidArea(g)
+	if area == 0 {
+		return orb.Point{}, ErrCollapsed
+	}
+
+	return centroid, nil
+}
+
+// LabelPoint computes a point guaranteed to lie inside a GeoJSON geometry.
+// It is the pole of inaccessibility of the polygon, or the largest polygon of a MultiPolygon.
+func LabelPoint(g orb.Geometry) (orb.Point, error) {
+	if g == nil {
+		return orb.Point{}, errors.New("invalid geometry")
+	}
+
+	if g.GeoJSONType() == geometryPolygon {
+		p, ok := g.(orb.Polygon)
+		if !ok {
+			return orb.Point{}, errors.New("invalid Polygon")
+		}
+
+		return labelPolygon(p)
+	}
+
+	if g.GeoJSONType() == geometryMultiPolygon {
+		mp, ok := g.(orb.MultiPolygon)
+		if !ok {
+			return orb.Point{}, errors.New("invalid MultiPolygon")
+		}
+
+		var largest orb.Polygon
+		max := 0.0
+		for _, p := range mp {
+			area := planar.Area(p)
+			if area > max {
+				largest, max = p, area
+			}
+		}
+
+		return labelPolygon(largest)
+	}
+
+	return orb.Point{}, errors.New("geometry type not supported")
+}
+
+func labelPolygon(p orb.Polygon) (orb.Point, error) {
+	if len(p) == 0 || len(p[0]) == 0 {
+		return orb.Point{}, ErrCollapsed
+	}
+
+	bound := p[0].Bound()
+	size := math.Max(bound.Max[0]-bound.Min[0], bound.Max[1]-bound.Min[1])
+	return PoleOfInaccessibility(p, size*constLabelPrecision), nil
+}
+
+// PoleOfInaccessibility finds the most distant internal point of a polygon from its outline.
+// The second parameter is the precision in coordinate units.
+// Credit: https://github.com/mapbox/polylabel
+func PoleOfInaccessibility(p orb.Polygon, precision float64) orb.Point {
+	if len(p) == 0 || len(p[0]) == 0 {
+		return orb.Point{}
+	}
+
+	bound := p[0].Bound()
+	width, height := bound.Max[0]-bound.Min[0], bound.Max[1]-bound.Min[1]
+	cellSize := math.Min(width, height)
+	if cellSize == 0 {
+		return bound.Min
+	}
+
+	// covering the polygon with initial cells
+	cells := &labelCells{}
+	h := cellSize / 2
+	for x := bound.Min[0]; x < bound.Max[0]; x += cellSize {
+		for y := bound.Min[1]; y < bound.Max[1]; y += cellSize {
+			heap.Push(cells, newLabelCell(orb.Point{x + h, y + h}, h, p))
+		}
+	}
+
+	// the centroid and the center of the bound are good initial guesses
+	centroid, _ := planar.CentroidArea(p)
+	best := newLabelCell(centroid, 0, p)
+	center := newLabelCell(bound.Center(), 0, p)
+	if center.d > best.d {
+		best = center
+	}
+
+	for cells.Len() > 0 {
+		cell := heap.Pop(cells).(labelCell)
+		if cell.d > best.d {
+			best = cell
+		}
+
+		// no better solution can be found within this cell
+		if cell.max-best.d <= precision {
+			continue
+		}
+
+		h := cell.h / 2
+		heap.Push(cells, newLabelCell(orb.Point{cell.center[0] - h, cell.center[1] - h}, h, p))
+		heap.Push(cells, newLabelCell(orb.Point{cell.center[0] + h, cell.center[1] - h}, h, p))
+		heap.Push(cells, newLabelCell(orb.Point{cell.center[0] - h, cell.center[1] + h}, h, p))
+		heap.Push(cells, newLabelCell(orb.Point{cell.center[0] + h, cell.center[1] + h}, h, p))
+	}
+
+	return best.center
+}
+
+type labelCell struct {
+	center orb.Point
+	h      float64 // half of the cell size
+	d      float64 // distance from the center to the polygon, negative if outside
+	max    float64 // max distance to the polygon within the cell
+}
+
+func newLabelCell(center orb.Point, h float64, p orb.Polygon) labelCell {
+	d := polygonSignedDistance(p, center)
+	return labelCell{center: center, h: h, d: d, max: d + h*math.Sqrt2}
+}
+
+// polygonSignedDistance computes the distance from a point to the outline of a polygon.
+// It's negative if the point is outside.
+func polygonSignedDistance(p orb.Polygon, point orb.Point) float64 {
+	min := math.Inf(1)
+	for _, ring := range p {
+		for i := 1; i < len(ring); i++ {
+			d := planar.DistanceFromSegmentSquared(ring[i-1], ring[i], point)
+			if d < min {
+				min = d
+			}
+		}
+	}
+
+	if planar.PolygonContains(p, point) {
+		return math.Sqrt(min)
+	}
+
+	return -math.Sqrt(min)
+}
+
+// labelCells is a max-heap of cells by their potential distance.
+type labelCells []labelCell
+
+func (cells labelCells) Len() int            { return len(cells) }
+func (cells labelCells) Less(i, j int) bool  { return cells[i].max > cells[j].max }
+func (cells labelCells) Swap(i, j int)       { cells[i], cells[j] = cells[j], cells[i] }
+func (cells *labelCells) Push(x interface{}) { *cells = append(*cells, x.(labelCell)) }
+func (cells *labelCells) Pop() interface{} {
+	old := *cells
+	n := len(old)
+	cell := old[n-1]
+	*cells = old[:n-1]
+	return cell
+}
@@ -0,0 +1,133 @@
+package geoutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+/*
+   F +-----------+ G
+     |           |
+     |   +---+   |
+     |   |   |   |
+     +---+   +---+
+   E             H
+*/
+var uShape = orb.Polygon{{
+	orb.Point{0, 0},
+	orb.Point{0, 3},
+	orb.Point{3, 3},
+	orb.Point{3, 0},
+	orb.Point{2, 0},
+	orb.Point{2, 2},
+	orb.Point{1, 2},
+	orb.Point{1, 0},
+	orb.Point{0, 0},
+}}
+
+func TestCentroid(t *testing.T) {
+	is := is.New(t)
+
+	_, err := Centroid(nil)
+	is.True(err != nil)
+
+	_, err = Centroid(orb.MultiPoint{})
+	is.True(err != nil)
+
+	_, err = Centroid(orb.Polygon{{orb.Point{1, 1}, orb.Point{1, 2}, orb.Point{1, 1}}})
+	is.Equal(err, ErrCollapsed)
+
+	centroid, err := Centroid(orb.Polygon{
+		{
+			orb.Point{0, 0},
+			orb.Point{0, 3},
+			orb.Point{3, 3},
+			orb.Point{3, 0},
+			orb.Point{0, 0},
+		},
+		{
+			orb.Point{0, 0},
+			orb.Point{0, 1},
+			orb.Point{1, 1},
+			orb.Point{1, 0},
+			orb.Point{0, 0},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(centroid, orb.Point{1.625, 1.625})
+
+	// the centroid of a U shape falls outside of it
+	centroid, err = Centroid(uShape)
+	is.NoErr(err)
+	is.True(!planar.PolygonContains(uShape, centroid))
+}
+
+func TestLabelPoint(t *testing.T) {
+	is := is.New(t)
+
+	_, err := LabelPoint(nil)
+	is.True(err != nil)
+
+	_, err = LabelPoint(&invalidPolygon{})
+	is.True(err != nil)
+
+	_, err = LabelPoint(&invalidMultiPolygon{})
+	is.True(err != nil)
+
+	_, err = LabelPoint(orb.MultiPoint{})
+	is.True(err != nil)
+
+	point, err := LabelPoint(uShape)
+	is.NoErr(err)
+	is.True(planar.PolygonContains(uShape, point))
+
+	// the largest polygon is labeled
+	point, err = LabelPoint(orb.MultiPolygon{
+		{{
+			orb.Point{10, 10},
+			orb.Point{10, 11},
+			orb.Point{11, 11},
+			orb.Point{11, 10},
+			orb.Point{10, 10},
+		}},
+		{{
+			orb.Point{0, 0},
+			orb.Point{0, 4},
+			orb.Point{4, 4},
+			orb.Point{4, 0},
+			orb.Point{0, 0},
+		}},
+	})
+	is.NoErr(err)
+	is.True(math.Abs(point[0]-2) < 1e-3)
+	is.True(math.Abs(point[1]-2) < 1e-3)
+}
+
+func TestPoleOfInaccessibilityHole(t *testing.T) {
+	is := is.New(t)
+
+	p := orb.Polygon{
+		{
+			orb.Point{0, 0},
+			orb.Point{0, 4},
+			orb.Point{4, 4},
+			orb.Point{4, 0},
+			orb.Point{0, 0},
+		},
+		{
+			orb.Point{1, 1},
+			orb.Point{1, 3},
+			orb.Point{3, 3},
+			orb.Point{3, 1},
+			orb.Point{1, 1},
+		},
+	}
+
+	point := PoleOfInaccessibility(p, 1e-4)
+	is.True(planar.PolygonContains(p, point))
+	is.Equal(PoleOfInaccessibility(orb.Polygon{}, 1), orb.Point{})
+}
@@ -0,0 +1,207 @@
+package geoutil
+
+import (
+	"errors"
+	"math"
+
+	"github.com/paulmach/orb"
+)
+
+// WGS84 ellipsoid
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+	wgs84B = wgs84A * (1 - wgs84F)
+)
+
+var (
+	wgs84E2 = wgs84F * (2 - wgs84F)
+	wgs84E  = math.Sqrt(wgs84E2)
+	// q at the pole, used to convert geodetic latitudes to authalic latitudes
+	wgs84QP = authalicQ(1)
+	// radius of the sphere having the same surface area as the ellipsoid
+	wgs84RQ = wgs84A * math.Sqrt(wgs84QP/2)
+)
+
+// GeodesicArea computes the area of a GeoJSON geometry on the WGS84 ellipsoid in square meters.
+// Holes are subtracted.
+// Latitudes are mapped onto the authalic sphere which preserves areas of the ellipsoid.
+// Edges are treated as great circles on that sphere, which is negligibly different from geodesics for OSM-sized edges.
+func GeodesicArea(g orb.Geometry) (float64, error) {
+	if g == nil {
+		return 0, errors.New("invalid geometry")
+	}
+
+	if g.GeoJSONType() == geometryPolygon {
+		p, ok := g.(orb.Polygon)
+		if !ok {
+			return 0, errors.New("invalid Polygon")
+		}
+
+		return polygonGeodesicArea(p), nil
+	}
+
+	if g.GeoJSONType() == geometryMultiPolygon {
+		mp, ok := g.(orb.MultiPolygon)
+		if !ok {
+			return 0, errors.New("invalid MultiPolygon")
+		}
+
+		area := 0.0
+		for _, p := range mp {
+			area += polygonGeodesicArea(p)
+		}
+
+		return area, nil
+	}
+
+	return 0, errors.New("geometry type not supported")
+}
+
+// GeodesicPerimeter computes the length of all rings of a GeoJSON geometry on the WGS84 ellipsoid in meters.
+// Holes are included because they are part of the boundary.
+func GeodesicPerimeter(g orb.Geometry) (float64, error) {
+	if g == nil {
+		return 0, errors.New("invalid geometry")
+	}
+
+	if g.GeoJSONType() == geometryPolygon {
+		p, ok := g.(orb.Polygon)
+		if !ok {
+			return 0, errors.New("invalid Polygon")
+		}
+
+		return polygonGeodesicPerimeter(p), nil
+	}
+
+	if g.GeoJSONType() == geometryMultiPolygon {
+		mp, ok := g.(orb.MultiPolygon)
+		if !ok {
+			return 0, errors.New("invalid MultiPolygon")
+		}
+
+		perimeter := 0.0
+		for _, p := range mp {
+			perimeter += polygonGeodesicPerimeter(p)
+		}
+
+		return perimeter, nil
+	}
+
+	return 0, errors.New("geometry type not supported")
+}
+
+func polygonGeodesicArea(p orb.Polygon) float64 {
+	if len(p) == 0 {
+		return 0
+	}
+
+	area := math.Abs(ringGeodesicArea(p[0]))
+	for i := 1; i < len(p); i++ {
+		area -= math.Abs(ringGeodesicArea(p[i]))
+	}
+
+	return area
+}
+
+func polygonGeodesicPerimeter(p orb.Polygon) float64 {
+	perimeter := 0.0
+	for _, ring := range p {
+		for i := 1; i < len(ring); i++ {
+			perimeter += GeodesicDistance(ring[i-1], ring[i])
+		}
+	}
+
+	return perimeter
+}
+
+// ringGeodesicArea computes the signed area of a ring with the spherical excess of each edge and the pole.
+func ringGeodesicArea(ring orb.Ring) float64 {
+	excess := 0.0
+	for i := 1; i < len(ring); i++ {
+		lambda1, lambda2 := deg2rad(ring[i-1][0]), deg2rad(ring[i][0])
+		beta1, beta2 := authalicLatitude(deg2rad(ring[i-1][1])), authalicLatitude(deg2rad(ring[i][1]))
+
+		dLambda := math.Remainder(lambda2-lambda1, 2*math.Pi)
+		t1, t2 := math.Tan(beta1/2), math.Tan(beta2/2)
+		excess += 2 * math.Atan2(math.Tan(dLambda/2)*(t1+t2), 1+t1*t2)
+	}
+
+	return excess * wgs84RQ * wgs84RQ
+}
+
+// GeodesicDistance computes the distance between two points on the WGS84 ellipsoid in meters.
+// Vincenty's inverse formula is used, falling back to the authalic sphere for nearly antipodal points.
+// Credit: https://www.movable-type.co.uk/scripts/latlong-vincenty.html
+func GeodesicDistance(p1, p2 orb.Point) float64 {
+	if p1 == p2 {
+		return 0
+	}
+
+	dLon := deg2rad(p2[0] - p1[0])
+	u1 := math.Atan((1 - wgs84F) * math.Tan(deg2rad(p1[1])))
+	u2 := math.Atan((1 - wgs84F) * math.Tan(deg2rad(p2[1])))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+
+	lambda := dLon
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma := math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0 // coincident points
+		}
+
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha := 1 - sinAlpha*sinAlpha
+
+		cos2SigmaM := 0.0 // equatorial line
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+
+		coefC := wgs84F / 16 * cos2Alpha * (4 + wgs84F*(4-3*cos2Alpha))
+		previous := lambda
+		lambda = dLon + (1-coefC)*wgs84F*sinAlpha*(sigma+coefC*sinSigma*(cos2SigmaM+coefC*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-previous) > 1e-12 {
+			continue
+		}
+
+		uSq := cos2Alpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+		coefA := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+		coefB := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+		deltaSigma := coefB * sinSigma * (cos2SigmaM + coefB/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-coefB/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+		return wgs84B * coefA * (sigma - deltaSigma)
+	}
+
+	// haversine on the authalic sphere
+	phi1, phi2 := deg2rad(p1[1]), deg2rad(p2[1])
+	dPhi, dLambda := phi2-phi1, deg2rad(p2[0]-p1[0])
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * wgs84RQ * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// authalicQ computes q of a latitude given its sine.
+func authalicQ(sinPhi float64) float64 {
+	esinPhi := wgs84E * sinPhi
+	return (1 - wgs84E2) * (sinPhi/(1-esinPhi*esinPhi) + math.Atanh(esinPhi)/wgs84E)
+}
+
+// authalicLatitude converts a geodetic latitude into the authalic latitude, both in radians.
+func authalicLatitude(phi float64) float64 {
+	ratio := authalicQ(math.Sin(phi)) / wgs84QP
+	if ratio > 1 {
+		ratio = 1
+	} else if ratio < -1 {
+		ratio = -1
+	}
+
+	return math.Asin(ratio)
+}
+
+func deg2rad(d float64) float64 {
+	return d * math.Pi / 180
+}
@@ -0,0 +1,114 @@
+package geoutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+)
+
+// almostEqual compares floats with a relative tolerance.
+func almostEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance*math.Abs(b)
+}
+
+func TestGeodesicDistance(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(GeodesicDistance(orb.Point{1, 1}, orb.Point{1, 1}), 0.0)
+
+	// one degree along the equator and along a meridian
+	is.True(almostEqual(GeodesicDistance(orb.Point{0, 0}, orb.Point{1, 0}), 111319.491, 1e-8))
+	is.True(almostEqual(GeodesicDistance(orb.Point{0, 0}, orb.Point{0, 1}), 110574.389, 1e-8))
+
+	// Flinders Peak to Buninyong: https://www.movable-type.co.uk/scripts/latlong-vincenty.html
+	is.True(almostEqual(GeodesicDistance(
+		orb.Point{144 + 25.0/60 + 29.52440/3600, -(37 + 57.0/60 + 3.72030/3600)},
+		orb.Point{143 + 55.0/60 + 35.38390/3600, -(37 + 39.0/60 + 10.15610/3600)},
+	), 54972.271, 1e-8))
+
+	// nearly antipodal points don't converge
+	is.True(almostEqual(GeodesicDistance(orb.Point{0, 0}, orb.Point{179.7, 0.5}), 19936288.579, 1e-2))
+}
+
+func TestGeodesicArea(t *testing.T) {
+	is := is.New(t)
+
+	_, err := GeodesicArea(nil)
+	is.True(err != nil)
+
+	_, err = GeodesicArea(&invalidPolygon{})
+	is.True(err != nil)
+
+	_, err = GeodesicArea(&invalidMultiPolygon{})
+	is.True(err != nil)
+
+	_, err = GeodesicArea(orb.MultiPoint{})
+	is.True(err != nil)
+
+	cell := orb.Ring{
+		orb.Point{0, 0},
+		orb.Point{1, 0},
+		orb.Point{1, 1},
+		orb.Point{0, 1},
+		orb.Point{0, 0},
+	}
+
+	// the area of a 1x1 degree cell at the equator is 12308778361 m2 (GeographicLib)
+	area, err := GeodesicArea(orb.Polygon{cell})
+	is.NoErr(err)
+	is.True(almostEqual(area, 12308778361, 1e-4))
+
+	// winding doesn't matter
+	reversed := append(orb.Ring{}, cell...)
+	RewindRing(reversed, true)
+	area, err = GeodesicArea(orb.MultiPolygon{{reversed}})
+	is.NoErr(err)
+	is.True(almostEqual(area, 12308778361, 1e-4))
+
+	hole := orb.Ring{
+		orb.Point{0.25, 0.25},
+		orb.Point{0.25, 0.75},
+		orb.Point{0.75, 0.75},
+		orb.Point{0.75, 0.25},
+		orb.Point{0.25, 0.25},
+	}
+
+	area, err = GeodesicArea(orb.Polygon{cell, hole})
+	is.NoErr(err)
+	is.True(almostEqual(area, 12308778361*0.75, 1e-3))
+}
+
+func TestGeodesicPerimeter(t *testing.T) {
+	is := is.New(t)
+
+	_, err := GeodesicPerimeter(nil)
+	is.True(err != nil)
+
+	_, err = GeodesicPerimeter(&invalidPolygon{})
+	is.True(err != nil)
+
+	_, err = GeodesicPerimeter(&invalidMultiPolygon{})
+	is.True(err != nil)
+
+	_, err = GeodesicPerimeter(orb.MultiPoint{})
+	is.True(err != nil)
+
+	square := orb.Ring{
+		orb.Point{0, 0},
+		orb.Point{0, 1},
+		orb.Point{1, 1},
+		orb.Point{1, 0},
+		orb.Point{0, 0},
+	}
+
+	perimeter, err := GeodesicPerimeter(orb.Polygon{square})
+	is.NoErr(err)
+	is.True(almostEqual(perimeter, 2*110574.389+111319.491+111302.649, 1e-5))
+
+	// holes are part of the boundary
+	withHole, err := GeodesicPerimeter(orb.MultiPolygon{{square, square}})
+	is.NoErr(err)
+	is.True(almostEqual(withHole, 2*perimeter, 1e-12))
+}
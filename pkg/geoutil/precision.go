@@ -0,0 +1,140 @@
+package geoutil
+
+import (
+	"errors"
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// ErrCollapsed is returned when a geometry no longer bounds any area after being processed.
+var ErrCollapsed = errors.New("geometry collapsed")
+
+// QuantizeFeatureCollection rounds coordinates of a GeoJSON feature collection.
+// The second parameter is the number of decimal places to keep.
+func QuantizeFeatureCollection(fc *geojson.FeatureCollection, precision int) error {
+	if fc == nil {
+		return errors.New("invalid feature collection")
+	}
+
+	for _, feature := range fc.Features {
+		err := QuantizeFeature(feature, precision)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QuantizeFeature rounds coordinates of a GeoJSON feature.
+// The second parameter is the number of decimal places to keep.
+func QuantizeFeature(f *geojson.Feature, precision int) error {
+	if f == nil {
+		return errors.New("invalid feature")
+	}
+
+	g, err := QuantizeGeometry(f.Geometry, precision)
+	if err != nil {
+		return err
+	}
+
+	f.Geometry = g
+	return nil
+}
+
+// QuantizeGeometry rounds coordinates of a GeoJSON geometry.
+// The second parameter is the number of decimal places to keep.
+// Consecutive duplicate points are removed and rings are re-closed afterwards.
+// Rings which collapse are dropped. ErrCollapsed is returned if nothing is left.
+func QuantizeGeometry(g orb.Geometry, precision int) (orb.Geometry, error) {
+	if g == nil {
+		return nil, errors.New("invalid geometry")
+	}
+
+	if precision < 0 {
+		return nil, errors.New("invalid precision")
+	}
+
+	if g.GeoJSONType() == geometryPolygon {
+		p, ok := g.(orb.Polygon)
+		if !ok {
+			return nil, errors.New("invalid Polygon")
+		}
+
+		p = QuantizePolygon(p, precision)
+		if p == nil {
+			return nil, ErrCollapsed
+		}
+
+		return p, nil
+	}
+
+	if g.GeoJSONType() == geometryMultiPolygon {
+		mp, ok := g.(orb.MultiPolygon)
+		if !ok {
+			return nil, errors.New("invalid MultiPolygon")
+		}
+
+		result := make(orb.MultiPolygon, 0, len(mp))
+		for _, p := range mp {
+			p = QuantizePolygon(p, precision)
+			if p == nil {
+				continue
+			}
+
+			result = append(result, p)
+		}
+
+		if len(result) == 0 {
+			return nil, ErrCollapsed
+		}
+
+		return result, nil
+	}
+
+	return nil, errors.New("geometry type not supported")
+}
+
+// QuantizePolygon rounds coordinates of a polygon.
+// The second parameter is the number of decimal places to keep.
+// Holes which collapse are dropped. Nil is returned if the outer ring collapses.
+func QuantizePolygon(p orb.Polygon, precision int) orb.Polygon {
+	if len(p) == 0 {
+		return nil
+	}
+
+	outer := QuantizeRing(p[0], precision)
+	if ringCollapsed(outer) {
+		return nil
+	}
+
+	result := orb.Polygon{outer}
+	for i := 1; i < len(p); i++ {
+		inner := QuantizeRing(p[i], precision)
+		if ringCollapsed(inner) {
+			continue
+		}
+
+		result = append(result, inner)
+	}
+
+	return result
+}
+
+// QuantizeRing rounds coordinates of a ring.
+// The second parameter is the number of decimal places to keep.
+// The result may be collapsed and should be validated by callers.
+func QuantizeRing(ring orb.Ring, precision int) orb.Ring {
+	factor := math.Pow10(precision)
+	result := make(orb.Ring, 0, len(ring))
+	for _, point := range ring {
+		result = append(result, orb.Point{
+			math.Round(point[0]*factor) / factor,
+			math.Round(point[1]*factor) / factor,
+		})
+	}
+
+	return closeRing(dedupeRing(result))
+}
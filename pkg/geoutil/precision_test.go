@@ -0,0 +1,146 @@
+package geoutil
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestQuantizeRing(t *testing.T) {
+	is := is.New(t)
+	ring := orb.Ring{
+		orb.Point{1.0001, 1.0002},
+		orb.Point{1.0003, 1.0004}, // duplicate after rounding
+		orb.Point{1, 2.0001},
+		orb.Point{2, 2},
+		orb.Point{2.0004, 1},
+		orb.Point{1.0001, 1.0002},
+	}
+
+	is.Equal(QuantizeRing(ring, 2), orb.Ring{
+		orb.Point{1, 1},
+		orb.Point{1, 2},
+		orb.Point{2, 2},
+		orb.Point{2, 1},
+		orb.Point{1, 1},
+	})
+}
+
+func TestQuantizeRingReclosed(t *testing.T) {
+	is := is.New(t)
+	ring := orb.Ring{
+		orb.Point{1, 1},
+		orb.Point{1, 2},
+		orb.Point{2, 2},
+		orb.Point{2, 1},
+		orb.Point{1.1, 1},
+	}
+
+	is.Equal(QuantizeRing(ring, 0), orb.Ring{
+		orb.Point{1, 1},
+		orb.Point{1, 2},
+		orb.Point{2, 2},
+		orb.Point{2, 1},
+		orb.Point{1, 1},
+	})
+}
+
+func TestQuantizePolygonCollapsedHole(t *testing.T) {
+	is := is.New(t)
+	p := orb.Polygon{
+		{
+			orb.Point{0, 0},
+			orb.Point{0, 3},
+			orb.Point{3, 3},
+			orb.Point{3, 0},
+			orb.Point{0, 0},
+		},
+		{
+			orb.Point{1.1, 1.1},
+			orb.Point{1.1, 1.2},
+			orb.Point{1.2, 1.2},
+			orb.Point{1.2, 1.1},
+			orb.Point{1.1, 1.1},
+		},
+	}
+
+	is.Equal(QuantizePolygon(p, 0), orb.Polygon{
+		{
+			orb.Point{0, 0},
+			orb.Point{0, 3},
+			orb.Point{3, 3},
+			orb.Point{3, 0},
+			orb.Point{0, 0},
+		},
+	})
+}
+
+func TestQuantizeGeometryInvalid(t *testing.T) {
+	is := is.New(t)
+
+	_, err := QuantizeGeometry(nil, 2)
+	is.True(err != nil)
+
+	_, err = QuantizeGeometry(orb.Polygon{}, -1)
+	is.True(err != nil)
+
+	_, err = QuantizeGeometry(&invalidPolygon{}, 2)
+	is.True(err != nil)
+
+	_, err = QuantizeGeometry(&invalidMultiPolygon{}, 2)
+	is.True(err != nil)
+
+	_, err = QuantizeGeometry(orb.MultiPoint(nil), 2)
+	is.True(err != nil)
+}
+
+func TestQuantizeGeometryCollapsed(t *testing.T) {
+	is := is.New(t)
+
+	_, err := QuantizeGeometry(orb.MultiPolygon{{{
+		orb.Point{1.1, 1.1},
+		orb.Point{1.1, 1.2},
+		orb.Point{1.2, 1.2},
+		orb.Point{1.2, 1.1},
+		orb.Point{1.1, 1.1},
+	}}}, 0)
+	is.Equal(err, ErrCollapsed)
+}
+
+func TestQuantizeFeatureCollection(t *testing.T) {
+	is := is.New(t)
+
+	err := QuantizeFeatureCollection(nil, 2)
+	is.True(err != nil)
+
+	fc := geojson.NewFeatureCollection()
+	fc.Append(geojson.NewFeature(orb.MultiPolygon{
+		{{
+			orb.Point{1.123, 1.123},
+			orb.Point{1.123, 2.123},
+			orb.Point{2.123, 2.123},
+			orb.Point{2.123, 1.123},
+			orb.Point{1.123, 1.123},
+		}},
+		{{
+			orb.Point{5.001, 5.001},
+			orb.Point{5.001, 5.002},
+			orb.Point{5.002, 5.002},
+			orb.Point{5.001, 5.001},
+		}},
+	}))
+
+	err = QuantizeFeatureCollection(fc, 1)
+	is.NoErr(err)
+	is.Equal(fc.Features[0].Geometry, orb.MultiPolygon{
+		{{
+			orb.Point{1.1, 1.1},
+			orb.Point{1.1, 2.1},
+			orb.Point{2.1, 2.1},
+			orb.Point{2.1, 1.1},
+			orb.Point{1.1, 1.1},
+		}},
+	})
+}
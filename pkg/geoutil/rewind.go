@@ -96,14 +96,7 @@ func RewindRings(rings []orb.Ring, outer bool) []orb.Ring {
 // RewindRing rewinds a GeoJSON ring.
 // The second parameter is the direction of winding. True means clockwise.
 func RewindRing(ring orb.Ring, cw bool) {
-	// Shoelace formula: https://mathworld.wolfram.com/PolygonArea.html
-	var area float64 = 0
-	len := len(ring)
-	for i, j := 0, len-1; i < len; i, j = i+1, i+1 {
-		area += (ring[i][0] - ring[j][0]) * (ring[j][1] + ring[i][1])
-	}
-
-	if area >= 0 != cw {
+	if ringArea(ring) >= 0 != cw {
 		util.ReverseAny(ring)
 	}
 }
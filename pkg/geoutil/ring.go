@@ -0,0 +1,59 @@
+package geoutil
+
+import (
+	"github.com/paulmach/orb"
+)
+
+// ringArea computes the signed area of a ring.
+// Positive values mean clockwise.
+func ringArea(ring orb.Ring) float64 {
+	// Shoelace formula: https://mathworld.wolfram.com/PolygonArea.html
+	var area float64 = 0
+	for i := range ring {
+		j := i - 1
+		if j < 0 {
+			j = len(ring) - 1
+		}
+
+		area += (ring[i][0] - ring[j][0]) * (ring[j][1] + ring[i][1])
+	}
+
+	return area / 2
+}
+
+// dedupeRing removes consecutive duplicate points of a ring.
+func dedupeRing(ring orb.Ring) orb.Ring {
+	result := make(orb.Ring, 0, len(ring))
+	for _, point := range ring {
+		if len(result) != 0 && result[len(result)-1] == point {
+			continue
+		}
+
+		result = append(result, point)
+	}
+
+	return result
+}
+
+// closeRing makes sure that the first and the last points of a ring are the same.
+func closeRing(ring orb.Ring) orb.Ring {
+	if len(ring) == 0 || ring.Closed() {
+		return ring
+	}
+
+	return append(ring, ring[0])
+}
+
+// openRing removes the closing points of a ring.
+func openRing(ring orb.Ring) orb.Ring {
+	for len(ring) > 1 && ring.Closed() {
+		ring = ring[:len(ring)-1]
+	}
+
+	return ring
+}
+
+// ringCollapsed determines if a ring no longer bounds any area.
+func ringCollapsed(ring orb.Ring) bool {
+	return len(ring) < 4 || !ring.Closed() || ringArea(ring) == 0
+}
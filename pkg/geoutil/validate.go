@@ -0,0 +1,503 @@
+package geoutil
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// IssueKind is the kind of a geometry issue.
+type IssueKind string
+
+const (
+	// IssueUnclosedRing means the first and the last points of a ring are different.
+	IssueUnclosedRing IssueKind = "unclosed-ring"
+	// IssueTooFewPoints means a ring has less than 4 points.
+	IssueTooFewPoints IssueKind = "too-few-points"
+	// IssueDuplicateVertex means a ring has consecutive duplicate points.
+	IssueDuplicateVertex IssueKind = "duplicate-vertex"
+	// IssueSpike means a ring goes back on itself at a point.
+	IssueSpike IssueKind = "spike"
+	// IssueSelfIntersection means rings of a polygon cross each other or themselves.
+	IssueSelfIntersection IssueKind = "self-intersection"
+)
+
+// Issue describes a problem of a geometry.
+// Polygon and Ring are the indexes of the offending polygon and ring.
+type Issue struct {
+	Kind    IssueKind `json:"kind"`
+	Polygon int       `json:"polygon"`
+	Ring    int       `json:"ring"`
+	Point   orb.Point `json:"point"`
+}
+
+// String implements fmt.Stringer.
+func (issue Issue) String() string {
+	return fmt.Sprintf("%s at %v (polygon %d, ring %d)", issue.Kind, issue.Point, issue.Polygon, issue.Ring)
+}
+
+// ValidateFeature looks for issues of a GeoJSON feature.
+func ValidateFeature(f *geojson.Feature) ([]Issue, error) {
+	if f == nil {
+		return nil, errors.New("invalid feature")
+	}
+
+	return ValidateGeometry(f.Geometry)
+}
+
+// ValidateGeometry looks for issues of a GeoJSON geometry.
+func ValidateGeometry(g orb.Geometry) ([]Issue, error) {
+	if g == nil {
+		return nil, errors.New("invalid geometry")
+	}
+
+	if g.GeoJSONType() == geometryPolygon {
+		p, ok := g.(orb.Polygon)
+		if !ok {
+			return nil, errors.New("invalid Polygon")
+		}
+
+		return ValidatePolygon(p, 0), nil
+	}
+
+	if g.GeoJSONType() == geometryMultiPolygon {
+		mp, ok := g.(orb.MultiPolygon)
+		if !ok {
+			return nil, errors.New("invalid MultiPolygon")
+		}
+
+		issues := []Issue{}
+		for i, p := range mp {
+			issues = append(issues, ValidatePolygon(p, i)...)
+		}
+
+		return issues, nil
+	}
+
+	return nil, errors.New("geometry type not supported")
+}
+
+// ValidatePolygon looks for issues of a polygon.
+// The second parameter is the index of the polygon used in reporting.
+func ValidatePolygon(p orb.Polygon, index int) []Issue {
+	issues := []Issue{}
+	for i, ring := range p {
+		for _, issue := range ValidateRing(ring) {
+			issue.Polygon = index
+			issue.Ring = i
+			issues = append(issues, issue)
+		}
+	}
+
+	for _, issue := range selfIntersections(p) {
+		issue.Polygon = index
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// ValidateRing looks for issues of a single ring.
+// Self-intersections are not checked here because they involve other rings of the polygon.
+func ValidateRing(ring orb.Ring) []Issue {
+	issues := []Issue{}
+	if len(ring) == 0 {
+		return append(issues, Issue{Kind: IssueTooFewPoints})
+	}
+
+	if !ring.Closed() {
+		issues = append(issues, Issue{Kind: IssueUnclosedRing, Point: ring[len(ring)-1]})
+	}
+
+	for i := 1; i < len(ring); i++ {
+		if ring[i] == ring[i-1] {
+			issues = append(issues, Issue{Kind: IssueDuplicateVertex, Point: ring[i]})
+		}
+	}
+
+	deduped := closeRing(dedupeRing(ring))
+	if len(deduped) < 4 {
+		return append(issues, Issue{Kind: IssueTooFewPoints, Point: ring[0]})
+	}
+
+	// the closing point is skipped because it's the first point
+	n := len(deduped) - 1
+	for i := 0; i < n; i++ {
+		prev, next := deduped[(i+n-1)%n], deduped[(i+1)%n]
+		if isSpike(prev, deduped[i], next) {
+			issues = append(issues, Issue{Kind: IssueSpike, Point: deduped[i]})
+		}
+	}
+
+	return issues
+}
+
+// RepairFeature repairs the geometry of a GeoJSON feature.
+func RepairFeature(f *geojson.Feature) error {
+	if f == nil {
+		return errors.New("invalid feature")
+	}
+
+	g, err := RepairGeometry(f.Geometry)
+	if err != nil {
+		return err
+	}
+
+	f.Geometry = g
+	return nil
+}
+
+// RepairGeometry fixes unclosed rings, duplicate vertices, spikes and rings crossing themselves of a GeoJSON geometry.
+// Rings which collapse are dropped. ErrCollapsed is returned if nothing is left.
+// A polygon may be split into a MultiPolygon. Rings crossing each other are left as they are and should be checked by ValidateGeometry afterwards.
+func RepairGeometry(g orb.Geometry) (orb.Geometry, error) {
+	if g == nil {
+		return nil, errors.New("invalid geometry")
+	}
+
+	if g.GeoJSONType() == geometryPolygon {
+		p, ok := g.(orb.Polygon)
+		if !ok {
+			return nil, errors.New("invalid Polygon")
+		}
+
+		parts := repairPolygon(p)
+		if len(parts) == 0 {
+			return nil, ErrCollapsed
+		}
+
+		if len(parts) == 1 {
+			return parts[0], nil
+		}
+
+		return orb.MultiPolygon(parts), nil
+	}
+
+	if g.GeoJSONType() == geometryMultiPolygon {
+		mp, ok := g.(orb.MultiPolygon)
+		if !ok {
+			return nil, errors.New("invalid MultiPolygon")
+		}
+
+		result := make(orb.MultiPolygon, 0, len(mp))
+		for _, p := range mp {
+			result = append(result, repairPolygon(p)...)
+		}
+
+		if len(result) == 0 {
+			return nil, ErrCollapsed
+		}
+
+		return result, nil
+	}
+
+	return nil, errors.New("geometry type not supported")
+}
+
+// RepairPolygon repairs rings of a polygon.
+// Holes which collapse are dropped. Nil is returned if the outer ring collapses.
+func RepairPolygon(p orb.Polygon) orb.Polygon {
+	if len(p) == 0 {
+		return nil
+	}
+
+	outer := RepairRing(p[0])
+	if ringCollapsed(outer) {
+		return nil
+	}
+
+	result := orb.Polygon{outer}
+	for i := 1; i < len(p); i++ {
+		inner := RepairRing(p[i])
+		if ringCollapsed(inner) {
+			continue
+		}
+
+		result = append(result, inner)
+	}
+
+	return result
+}
+
+// repairPolygon repairs rings of a polygon and splits it where they cross themselves.
+func repairPolygon(p orb.Polygon) []orb.Polygon {
+	rings := make(orb.Polygon, 0, len(p))
+	crossed := false
+	for _, ring := range p {
+		ring = RepairRing(ring)
+		_, _, _, ok := ringCrossing(ring)
+		crossed = crossed || ok
+		rings = append(rings, ring)
+	}
+
+	if crossed {
+		return splitPolygon(rings)
+	}
+
+	rings = RepairPolygon(rings)
+	if rings == nil {
+		return nil
+	}
+
+	return []orb.Polygon{rings}
+}
+
+// RepairRing closes a ring and removes its duplicate vertices and spikes.
+// The result may be collapsed and should be validated by callers.
+func RepairRing(ring orb.Ring) orb.Ring {
+	ring = openRing(dedupeRing(ring))
+
+	// removing a spike may reveal another one so we keep going until nothing changes
+	for removed := true; removed && len(ring) >= 3; {
+		removed = false
+		n := len(ring)
+		for i := 0; i < n; i++ {
+			prev, next := ring[(i+n-1)%n], ring[(i+1)%n]
+			if !isSpike(prev, ring[i], next) {
+				continue
+			}
+
+			ring = openRing(dedupeRing(append(ring[:i:i], ring[i+1:]...)))
+			removed = true
+			break
+		}
+	}
+
+	return closeRing(ring)
+}
+
+// isSpike determines if the path a -> b -> c goes back on itself at b.
+func isSpike(a, b, c orb.Point) bool {
+	if cross(a, b, c) != 0 {
+		return false
+	}
+
+	return (b[0]-a[0])*(c[0]-b[0])+(b[1]-a[1])*(c[1]-b[1]) < 0
+}
+
+// cross computes the cross product of vectors a -> b and a -> c.
+func cross(a, b, c orb.Point) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+type edge struct {
+	a, b  orb.Point
+	ring  int
+	index int
+	last  bool
+}
+
+func (e edge) minX() float64 {
+	if e.a[0] < e.b[0] {
+		return e.a[0]
+	}
+
+	return e.b[0]
+}
+
+func (e edge) maxX() float64 {
+	if e.a[0] > e.b[0] {
+		return e.a[0]
+	}
+
+	return e.b[0]
+}
+
+// adjacent determines if two edges of the same ring share an endpoint by construction.
+func (e edge) adjacent(other edge) bool {
+	if e.ring != other.ring {
+		return false
+	}
+
+	d := e.index - other.index
+	if d == 1 || d == -1 {
+		return true
+	}
+
+	return (e.index == 0 && other.last) || (other.index == 0 && e.last)
+}
+
+// selfIntersections looks for crossing edges of a polygon with a sweep along the X axis.
+func selfIntersections(p orb.Polygon) []Issue {
+	edges := []edge{}
+	for i, ring := range p {
+		ring = dedupeRing(ring)
+		for j := 1; j < len(ring); j++ {
+			edges = append(edges, edge{a: ring[j-1], b: ring[j], ring: i, index: j - 1, last: j == len(ring)-1})
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].minX() < edges[j].minX()
+	})
+
+	issues := []Issue{}
+	for i := range edges {
+		for j := i + 1; j < len(edges) && edges[j].minX() <= edges[i].maxX(); j++ {
+			if edges[i].adjacent(edges[j]) {
+				continue
+			}
+
+			point, ok := crossing(edges[i], edges[j])
+			if !ok {
+				continue
+			}
+
+			issues = append(issues, Issue{Kind: IssueSelfIntersection, Ring: edges[i].ring, Point: point})
+		}
+	}
+
+	return issues
+}
+
+// crossing finds the point where two edges properly cross each other.
+func crossing(e, f edge) (orb.Point, bool) {
+	d1, d2 := cross(e.a, e.b, f.a), cross(e.a, e.b, f.b)
+	d3, d4 := cross(f.a, f.b, e.a), cross(f.a, f.b, e.b)
+	if d1*d2 >= 0 || d3*d4 >= 0 {
+		return orb.Point{}, false
+	}
+
+	t := d3 / (d3 - d4)
+	return orb.Point{e.a[0] + t*(e.b[0]-e.a[0]), e.a[1] + t*(e.b[1]-e.a[1])}, true
+}
+
+// ringCrossing finds the first pair of edges of a ring which cross each other.
+// Edges are indexed by their first points.
+func ringCrossing(ring orb.Ring) (int, int, orb.Point, bool) {
+	edges := make([]edge, 0, len(ring))
+	for j := 1; j < len(ring); j++ {
+		edges = append(edges, edge{a: ring[j-1], b: ring[j], index: j - 1, last: j == len(ring)-1})
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].minX() < edges[j].minX()
+	})
+
+	for i := range edges {
+		for j := i + 1; j < len(edges) && edges[j].minX() <= edges[i].maxX(); j++ {
+			if edges[i].adjacent(edges[j]) {
+				continue
+			}
+
+			point, ok := crossing(edges[i], edges[j])
+			if !ok {
+				continue
+			}
+
+			if edges[i].index < edges[j].index {
+				return edges[i].index, edges[j].index, point, true
+			}
+
+			return edges[j].index, edges[i].index, point, true
+		}
+	}
+
+	return 0, 0, orb.Point{}, false
+}
+
+// splitRing splits a ring at the points where it crosses itself into rings which don't, e.g. a bow-tie into two triangles.
+// Rings which collapse are dropped.
+func splitRing(ring orb.Ring) []orb.Ring {
+	ring = closeRing(dedupeRing(ring))
+	i, j, point, ok := ringCrossing(ring)
+	if !ok {
+		if ringCollapsed(ring) {
+			return nil
+		}
+
+		return []orb.Ring{ring}
+	}
+
+	// the edges i and j cross at the point: the ring goes around one loop before the point and the other one after it
+	first := append(append(append(orb.Ring{}, ring[:i+1]...), point), ring[j+1:]...)
+	second := append(append(orb.Ring{point}, ring[i+1:j+1]...), point)
+	return append(splitRing(first), splitRing(second)...)
+}
+
+// ringWithin determines if a ring lies inside another one which it doesn't cross.
+// Points shared by both rings are skipped since they are on the boundary.
+func ringWithin(ring, other orb.Ring) bool {
+	shared := map[orb.Point]bool{}
+	for _, point := range other {
+		shared[point] = true
+	}
+
+	for _, point := range ring {
+		if !shared[point] {
+			return planar.RingContains(other, point)
+		}
+	}
+
+	return false
+}
+
+// splitPolygon splits a polygon whose rings cross themselves into polygons whose rings don't.
+// Loops of the outer ring which lie inside other loops become their holes, as the even-odd rule fills them.
+// Holes are kept in the innermost part containing them and dropped if none does.
+func splitPolygon(p orb.Polygon) []orb.Polygon {
+	loops := splitRing(p[0])
+	holes := []orb.Ring{}
+	for i := 1; i < len(p); i++ {
+		holes = append(holes, splitRing(p[i])...)
+	}
+
+	// depth is the number of loops containing a loop, parent is the innermost of them
+	depth := make([]int, len(loops))
+	parent := make([]int, len(loops))
+	for i := range loops {
+		parent[i] = -1
+		for j := range loops {
+			if i != j && ringWithin(loops[i], loops[j]) {
+				depth[i]++
+			}
+		}
+	}
+
+	for i := range loops {
+		for j := range loops {
+			if i != j && depth[j] == depth[i]-1 && ringWithin(loops[i], loops[j]) {
+				parent[i] = j
+			}
+		}
+	}
+
+	parts := map[int]orb.Polygon{}
+	order := []int{}
+	for i, loop := range loops {
+		if depth[i]%2 == 0 {
+			parts[i] = orb.Polygon{loop}
+			order = append(order, i)
+		}
+	}
+
+	for i, loop := range loops {
+		if depth[i]%2 == 1 && parent[i] >= 0 {
+			parts[parent[i]] = append(parts[parent[i]], loop)
+		}
+	}
+
+	for _, hole := range holes {
+		innermost := -1
+		for _, i := range order {
+			if ringWithin(hole, loops[i]) && (innermost < 0 || depth[i] > depth[innermost]) {
+				innermost = i
+			}
+		}
+
+		if innermost >= 0 {
+			parts[innermost] = append(parts[innermost], hole)
+		}
+	}
+
+	result := make([]orb.Polygon, 0, len(order))
+	for _, i := range order {
+		result = append(result, parts[i])
+	}
+
+	return result
+}
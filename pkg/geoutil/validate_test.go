@@ -0,0 +1,270 @@
+package geoutil
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestValidateRing(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(ValidateRing(orb.Ring{}), []Issue{{Kind: IssueTooFewPoints}})
+	is.Equal(ValidateRing(orb.Ring{
+		orb.Point{1, 1},
+		orb.Point{1, 2},
+		orb.Point{2, 2},
+		orb.Point{2, 1},
+		orb.Point{1, 1},
+	}), []Issue{})
+
+	is.Equal(ValidateRing(orb.Ring{
+		orb.Point{1, 1},
+		orb.Point{1, 2},
+		orb.Point{1, 2},
+		orb.Point{2, 2},
+		orb.Point{2, 1},
+	}), []Issue{
+		{Kind: IssueUnclosedRing, Point: orb.Point{2, 1}},
+		{Kind: IssueDuplicateVertex, Point: orb.Point{1, 2}},
+	})
+
+	is.Equal(ValidateRing(orb.Ring{
+		orb.Point{1, 1},
+		orb.Point{1, 2},
+		orb.Point{1, 1},
+	}), []Issue{{Kind: IssueTooFewPoints, Point: orb.Point{1, 1}}})
+}
+
+/*
+B +-------+ C
+
+	|       |
+	|       +---+ E
+	|       |
+
+A +-------+ D
+*/
+func TestValidateRingSpike(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(ValidateRing(orb.Ring{
+		orb.Point{1, 1}, // A
+		orb.Point{1, 3}, // B
+		orb.Point{3, 3}, // C
+		orb.Point{3, 2}, // C-D
+		orb.Point{4, 2}, // E
+		orb.Point{3, 2}, // C-D
+		orb.Point{3, 1}, // D
+		orb.Point{1, 1}, // A
+	}), []Issue{{Kind: IssueSpike, Point: orb.Point{4, 2}}})
+}
+
+/*
+B +   + C
+
+	|\ /|
+	| X |
+	|/ \|
+
+A +   + D
+*/
+func TestValidatePolygonSelfIntersection(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(ValidatePolygon(orb.Polygon{{
+		orb.Point{1, 1}, // A
+		orb.Point{1, 3}, // B
+		orb.Point{3, 1}, // D
+		orb.Point{3, 3}, // C
+		orb.Point{1, 1}, // A
+	}}, 2), []Issue{{Kind: IssueSelfIntersection, Polygon: 2, Point: orb.Point{2, 2}}})
+
+	// the hole crosses the outer ring
+	is.Equal(ValidatePolygon(orb.Polygon{
+		{
+			orb.Point{0, 0},
+			orb.Point{0, 2},
+			orb.Point{2, 2},
+			orb.Point{2, 0},
+			orb.Point{0, 0},
+		},
+		{
+			orb.Point{1, 1},
+			orb.Point{1, 3},
+			orb.Point{1.5, 3},
+			orb.Point{1.5, 1},
+			orb.Point{1, 1},
+		},
+	}, 0), []Issue{
+		{Kind: IssueSelfIntersection, Ring: 0, Point: orb.Point{1, 2}},
+		{Kind: IssueSelfIntersection, Ring: 0, Point: orb.Point{1.5, 2}},
+	})
+}
+
+func TestValidateGeometryInvalid(t *testing.T) {
+	is := is.New(t)
+
+	_, err := ValidateGeometry(nil)
+	is.True(err != nil)
+
+	_, err = ValidateGeometry(&invalidPolygon{})
+	is.True(err != nil)
+
+	_, err = ValidateGeometry(&invalidMultiPolygon{})
+	is.True(err != nil)
+
+	_, err = ValidateGeometry(orb.MultiPoint(nil))
+	is.True(err != nil)
+
+	_, err = ValidateFeature(nil)
+	is.True(err != nil)
+}
+
+func TestRepairRing(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(RepairRing(orb.Ring{
+		orb.Point{1, 1}, // A
+		orb.Point{1, 3}, // B
+		orb.Point{1, 3}, // B
+		orb.Point{3, 3}, // C
+		orb.Point{3, 2}, // C-D
+		orb.Point{4, 2}, // E
+		orb.Point{5, 2}, // E'
+		orb.Point{4, 2}, // E
+		orb.Point{3, 2}, // C-D
+		orb.Point{3, 1}, // D
+	}), orb.Ring{
+		orb.Point{1, 1}, // A
+		orb.Point{1, 3}, // B
+		orb.Point{3, 3}, // C
+		orb.Point{3, 2}, // C-D
+		orb.Point{3, 1}, // D
+		orb.Point{1, 1}, // A
+	})
+
+	is.True(ringCollapsed(RepairRing(orb.Ring{
+		orb.Point{1, 1},
+		orb.Point{1, 3},
+		orb.Point{1, 1},
+		orb.Point{1, 1},
+	})))
+}
+
+func TestRepairFeature(t *testing.T) {
+	is := is.New(t)
+
+	err := RepairFeature(nil)
+	is.True(err != nil)
+
+	feature := geojson.NewFeature(orb.MultiPolygon{
+		{{
+			orb.Point{1, 1},
+			orb.Point{1, 2},
+			orb.Point{2, 2},
+			orb.Point{2, 2},
+			orb.Point{2, 1},
+		}},
+		{{
+			orb.Point{5, 5},
+			orb.Point{5, 6},
+			orb.Point{5, 5},
+		}},
+	})
+
+	err = RepairFeature(feature)
+	is.NoErr(err)
+	is.Equal(feature.Geometry, orb.MultiPolygon{
+		{{
+			orb.Point{1, 1},
+			orb.Point{1, 2},
+			orb.Point{2, 2},
+			orb.Point{2, 1},
+			orb.Point{1, 1},
+		}},
+	})
+
+	issues, err := ValidateFeature(feature)
+	is.NoErr(err)
+	is.Equal(len(issues), 0)
+
+	_, err = RepairGeometry(orb.Polygon{{
+		orb.Point{5, 5},
+		orb.Point{5, 6},
+		orb.Point{5, 5},
+	}})
+	is.Equal(err, ErrCollapsed)
+}
+
+/*
+B +   + C
+
+	|\ /|
+	| X |
+	|/ \|
+
+A +   + D
+*/
+func TestRepairGeometrySelfIntersection(t *testing.T) {
+	is := is.New(t)
+
+	g, err := RepairGeometry(orb.Polygon{{
+		orb.Point{1, 1}, // A
+		orb.Point{1, 3}, // B
+		orb.Point{3, 1}, // D
+		orb.Point{3, 3}, // C
+		orb.Point{1, 1}, // A
+	}})
+	is.NoErr(err)
+	is.Equal(g, orb.MultiPolygon{
+		{{orb.Point{1, 1}, orb.Point{1, 3}, orb.Point{2, 2}, orb.Point{1, 1}}},
+		{{orb.Point{2, 2}, orb.Point{3, 1}, orb.Point{3, 3}, orb.Point{2, 2}}},
+	})
+
+	issues, err := ValidateGeometry(g)
+	is.NoErr(err)
+	is.Equal(len(issues), 0)
+
+	// the hole stays in the half containing it
+	g, err = RepairGeometry(orb.MultiPolygon{{
+		{
+			orb.Point{0, 0},
+			orb.Point{0, 4},
+			orb.Point{4, 0},
+			orb.Point{4, 4},
+			orb.Point{0, 0},
+		},
+		{
+			orb.Point{0.5, 1.5},
+			orb.Point{1, 1.5},
+			orb.Point{1, 2.5},
+			orb.Point{0.5, 2.5},
+			orb.Point{0.5, 1.5},
+		},
+	}})
+	is.NoErr(err)
+	is.Equal(len(g.(orb.MultiPolygon)), 2)
+	is.Equal(len(g.(orb.MultiPolygon)[0]), 2)
+	is.Equal(len(g.(orb.MultiPolygon)[1]), 1)
+
+	// a loop inside the other one becomes its hole
+	g, err = RepairGeometry(orb.Polygon{{
+		orb.Point{0, 0},
+		orb.Point{0, 4},
+		orb.Point{4, 4},
+		orb.Point{4, 1},
+		orb.Point{2, 1},
+		orb.Point{2, 3},
+		orb.Point{3, 3},
+		orb.Point{3, 0},
+		orb.Point{0, 0},
+	}})
+	is.NoErr(err)
+	is.Equal(g, orb.Polygon{
+		{orb.Point{0, 0}, orb.Point{0, 4}, orb.Point{4, 4}, orb.Point{4, 1}, orb.Point{3, 1}, orb.Point{3, 0}, orb.Point{0, 0}},
+		{orb.Point{3, 1}, orb.Point{2, 1}, orb.Point{2, 3}, orb.Point{3, 3}, orb.Point{3, 1}},
+	})
+}
@@ -0,0 +1,155 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Content encodings
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+)
+
+// CompressionLevel trades the speed of compressors for the size of their outputs.
+type CompressionLevel int
+
+// Compression levels
+const (
+	// CompressionFast suits compressing on the fly, e.g. HTTP responses.
+	CompressionFast CompressionLevel = iota
+	// CompressionBest suits compressing once and serving many times, e.g. sidecars.
+	CompressionBest
+)
+
+// compressionLevels maps compression levels to the levels of each content encoding.
+var compressionLevels = map[string]map[CompressionLevel]int{
+	EncodingGzip: {
+		CompressionFast: gzip.DefaultCompression,
+		CompressionBest: gzip.BestCompression,
+	},
+	EncodingBrotli: {
+		CompressionFast: 4,
+		CompressionBest: brotli.BestCompression,
+	},
+}
+
+// encodingExtensions maps content encodings to file extensions.
+var encodingExtensions = map[string]string{
+	EncodingGzip:   ".gz",
+	EncodingBrotli: ".br",
+}
+
+// ParseEncodings validates content encodings: gzip or br.
+func ParseEncodings(encodings []string) ([]string, error) {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, encoding := range encodings {
+		encoding = strings.TrimSpace(encoding)
+		_, ok := encodingExtensions[encoding]
+		if !ok {
+			return nil, errors.New("invalid encoding")
+		}
+
+		if seen[encoding] {
+			continue
+		}
+
+		seen[encoding] = true
+		result = append(result, encoding)
+	}
+
+	return result, nil
+}
+
+// EncodingExtension returns the file extension of a content encoding, e.g. ".gz" of gzip.
+func EncodingExtension(encoding string) string {
+	return encodingExtensions[encoding]
+}
+
+// NewEncoder wraps a writer with the compressor of a content encoding at a compression level.
+func NewEncoder(w io.Writer, encoding string, level CompressionLevel) (io.WriteCloser, error) {
+	l, ok := compressionLevels[encoding][level]
+	if !ok {
+		return nil, errors.New("invalid encoding")
+	}
+
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewWriterLevel(w, l)
+	case EncodingBrotli:
+		return brotli.NewWriterLevel(w, l), nil
+	}
+
+	return nil, errors.New("invalid encoding")
+}
+
+// Compress compresses data with a content encoding at a compression level.
+func Compress(data []byte, encoding string, level CompressionLevel) ([]byte, error) {
+	var b bytes.Buffer
+	encoder, err := NewEncoder(&b, encoding, level)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = encoder.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// NegotiateEncoding picks the most preferred encoding of a request among the offered ones, in order of the offer for ties.
+// It returns an empty string if none of them is acceptable.
+func NegotiateEncoding(r *http.Request, offers ...string) string {
+	weights := map[string]float64{}
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(part, ";")
+		encoding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if encoding == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				q = v
+			}
+		}
+
+		weights[encoding] = q
+	}
+
+	best, max := "", 0.0
+	for _, offer := range offers {
+		q, ok := weights[offer]
+		if !ok {
+			q, ok = weights["*"]
+		}
+
+		if ok && q > max {
+			best, max = offer, q
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,86 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/matryer/is"
+)
+
+func TestParseEncodings(t *testing.T) {
+	is := is.New(t)
+
+	encodings, err := ParseEncodings([]string{"br", " gzip", "br"})
+	is.NoErr(err)
+	is.Equal(encodings, []string{"br", "gzip"})
+
+	_, err = ParseEncodings([]string{"deflate"})
+	is.True(err != nil)
+}
+
+func TestCompress(t *testing.T) {
+	is := is.New(t)
+	data := bytes.Repeat([]byte(`{"type":"FeatureCollection"}`), 100)
+
+	decompress := map[string]func([]byte) ([]byte, error){
+		EncodingGzip: func(compressed []byte) ([]byte, error) {
+			reader, err := gzip.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				return nil, err
+			}
+
+			return ioutil.ReadAll(reader)
+		},
+		EncodingBrotli: func(compressed []byte) ([]byte, error) {
+			return ioutil.ReadAll(brotli.NewReader(bytes.NewReader(compressed)))
+		},
+	}
+
+	tests := []struct {
+		encoding string
+		level    CompressionLevel
+	}{
+		{EncodingGzip, CompressionFast},
+		{EncodingGzip, CompressionBest},
+		{EncodingBrotli, CompressionFast},
+		{EncodingBrotli, CompressionBest},
+	}
+
+	for _, tt := range tests {
+		compressed, err := Compress(data, tt.encoding, tt.level)
+		is.NoErr(err)
+		is.True(len(compressed) < len(data))
+
+		decompressed, err := decompress[tt.encoding](compressed)
+		is.NoErr(err)
+		is.Equal(decompressed, data)
+	}
+
+	_, err := Compress(data, "deflate", CompressionBest)
+	is.True(err != nil)
+
+	_, err = Compress(data, EncodingGzip, CompressionLevel(-1))
+	is.True(err != nil)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	is := is.New(t)
+
+	negotiate := func(header string) string {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		return NegotiateEncoding(r, EncodingBrotli, EncodingGzip)
+	}
+
+	is.Equal(negotiate(""), "")
+	is.Equal(negotiate("gzip, deflate"), "gzip")
+	is.Equal(negotiate("gzip, deflate, br"), "br")
+	is.Equal(negotiate("br;q=0.5, gzip"), "gzip")
+	is.Equal(negotiate("br;q=0, gzip;q=0"), "")
+	is.Equal(negotiate("*"), "br")
+	is.Equal(negotiate("identity"), "")
+}
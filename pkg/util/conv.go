@@ -1,7 +1,10 @@
 package util
 
 import (
+	"bufio"
+	"io"
 	"strconv"
+	"strings"
 )
 
 // Int64FromString interprets a string s in base 64 with bit size of 64.
@@ -13,3 +16,31 @@ func Int64FromString(s string) (int64, error) {
 
 	return int64(int), nil
 }
+
+// ReadIDs reads IDs separated by whitespaces or commas. Text after "#" is a comment.
+func ReadIDs(r io.Reader) ([]int64, error) {
+	ids := []int64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.Index(line, "#")
+		if i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+
+		for _, field := range fields {
+			id, err := Int64FromString(field)
+			if err != nil {
+				return nil, err
+			}
+
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, scanner.Err()
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/matryer/is"
@@ -16,3 +17,18 @@ func TestInt64FromString(t *testing.T) {
 	is.NoErr(err)
 	is.Equal(x, int64(12))
 }
+
+func TestReadIDs(t *testing.T) {
+	is := is.New(t)
+
+	ids, err := ReadIDs(strings.NewReader("49915 # Vietnam\n\n1902,  1903\n\t# comment only\n61320"))
+	is.NoErr(err)
+	is.Equal(ids, []int64{49915, 1902, 1903, 61320})
+
+	ids, err = ReadIDs(strings.NewReader(""))
+	is.NoErr(err)
+	is.Equal(ids, []int64{})
+
+	_, err = ReadIDs(strings.NewReader("1 a"))
+	is.True(err != nil)
+}
@@ -1,2 +1,2 @@
-// Package util provides utilities which includes type conversion, HTTP shortcuts, string manipulcation & time parsing.
+// Package util provides utilities which includes type conversion, HTTP shortcuts, string manipulcation, language & time parsing.
 package util
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// ParseLanguages canonicalizes BCP 47 language tags, e.g. "vi", "en" or "zh-Hans".
+// Duplicates and empty tags are dropped.
+func ParseLanguages(tags []string) ([]string, error) {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		t, err := language.Parse(tag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid language: %s", tag)
+		}
+
+		str := t.String()
+		if seen[str] {
+			continue
+		}
+
+		seen[str] = true
+		result = append(result, str)
+	}
+
+	return result, nil
+}
+
+// ParseAcceptLanguage extracts acceptable base languages of an Accept-Language header in the order of preference.
+// E.g. "en-US" becomes "en".
+func ParseAcceptLanguage(header string) []string {
+	tags, q, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return []string{}
+	}
+
+	strs := []string{}
+	for i, tag := range tags {
+		base, confidence := tag.Base()
+		// "*" is parsed as "mul" (multiple languages)
+		if q[i] <= 0 || confidence == language.No || base.String() == "mul" {
+			continue
+		}
+
+		strs = append(strs, base.String())
+	}
+
+	result, err := ParseLanguages(strs)
+	if err != nil {
+		return []string{}
+	}
+
+	return result
+}
@@ -0,0 +1,26 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParseLanguages(t *testing.T) {
+	is := is.New(t)
+
+	_, err := ParseLanguages([]string{"vi", "../../etc"})
+	is.True(err != nil)
+
+	langs, err := ParseLanguages([]string{"vi", " EN ", "", "vi", "zh-hans"})
+	is.NoErr(err)
+	is.Equal(langs, []string{"vi", "en", "zh-Hans"})
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(ParseAcceptLanguage(""), []string{})
+	is.Equal(ParseAcceptLanguage("en-US;q=0.8, vi, en;q=0.5, fr;q=0, *;q=0.1"), []string{"vi", "en"})
+	is.Equal(ParseAcceptLanguage("en;q=x"), []string{})
+}
@@ -0,0 +1,144 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	hangulFirst      = 0xAC00
+	hangulLast       = 0xD7A3
+	hangulSilent     = 11 // the initial ㅇ
+	katakanaFirst    = 0x30A1
+	katakanaLast     = 0x30F6
+	katakanaHiragana = 0x60 // the offset of Katakana from Hiragana
+)
+
+// romanize transliterates a syllable of Hangul or a mora of Kana starting at i.
+// The number of consumed runes is returned along with the ASCII form, 0 means the rune is neither Hangul nor Kana.
+func romanize(runes []rune, i int) (string, int) {
+	if runes[i] >= hangulFirst && runes[i] <= hangulLast {
+		return romanizeHangul(runes, i), 1
+	}
+
+	return romanizeKana(runes, i)
+}
+
+// romanizeHangul follows the Revised Romanization of Korean without assimilation across syllables.
+// A final consonant followed by a silent initial is pronounced as that initial, e.g. "한국어" becomes "hangugeo".
+func romanizeHangul(runes []rune, i int) string {
+	s := int(runes[i] - hangulFirst)
+	initial, medial, final := s/588, (s%588)/28, s%28
+	str := hangulInitials[initial] + hangulMedials[medial]
+	if final == 0 {
+		return str
+	}
+
+	if i+1 < len(runes) && runes[i+1] >= hangulFirst && runes[i+1] <= hangulLast && int(runes[i+1]-hangulFirst)/588 == hangulSilent {
+		return str + hangulLinkedFinals[final]
+	}
+
+	return str + hangulFinals[final]
+}
+
+// romanizeKana follows the Hepburn romanization without macrons, e.g. "さっぽろ" becomes "sapporo".
+func romanizeKana(runes []rune, i int) (string, int) {
+	r := hiragana(runes[i])
+	if r == 'っ' {
+		// the small tsu doubles the consonant of the next mora
+		if i+1 < len(runes) {
+			next, n := romanizeKana(runes, i+1)
+			if n != 0 && next != "" && !strings.ContainsAny(next[:1], "aiueo") {
+				if strings.HasPrefix(next, "ch") {
+					return "t" + next, n + 1
+				}
+
+				return next[:1] + next, n + 1
+			}
+		}
+
+		return "", 1
+	}
+
+	base, ok := kanaRules[r]
+	if !ok {
+		return "", 0
+	}
+
+	// a small ya, yu or yo joins the previous mora, e.g. "きょ" becomes "kyo" and "しゃ" becomes "sha"
+	if i+1 < len(runes) && len(base) > 1 && strings.HasSuffix(base, "i") {
+		if vowel, ok := kanaSmallY[hiragana(runes[i+1])]; ok {
+			stem := base[:len(base)-1]
+			if stem == "sh" || stem == "ch" || stem == "j" {
+				return stem + vowel, 2
+			}
+
+			return stem + "y" + vowel, 2
+		}
+	}
+
+	return base, 1
+}
+
+// hiragana maps Katakana to Hiragana.
+func hiragana(r rune) rune {
+	if r >= katakanaFirst && r <= katakanaLast {
+		return r - katakanaHiragana
+	}
+
+	return r
+}
+
+// escapeASCII replaces runes which are still not ASCII with their code points, e.g. "東" becomes "u6771".
+func escapeASCII(str string) string {
+	var b strings.Builder
+	for _, r := range str {
+		if r < 0x80 {
+			b.WriteRune(r)
+			continue
+		}
+
+		fmt.Fprintf(&b, "u%04x", r)
+	}
+
+	return b.String()
+}
+
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulMedials  = []string{
+		"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae",
+		"oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i",
+	}
+	hangulFinals = []string{
+		"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l",
+		"p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t",
+	}
+	hangulLinkedFinals = []string{
+		"", "g", "kk", "ks", "n", "nj", "n", "d", "r", "lg", "lm", "lb", "ls", "lt",
+		"lp", "r", "m", "b", "ps", "s", "ss", "ng", "j", "ch", "k", "t", "p", "",
+	}
+)
+
+// kanaRules maps Hiragana to ASCII. Katakana is mapped to Hiragana beforehand.
+var kanaRules = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o", 'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo", 'ゎ': "wa",
+	'ー': "", '・': " ",
+}
+
+var kanaSmallY = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
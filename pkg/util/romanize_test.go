@@ -0,0 +1,38 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestRomanize(t *testing.T) {
+	is := is.New(t)
+
+	romanized, n := romanize([]rune("국어"), 0)
+	is.Equal(romanized, "gug")
+	is.Equal(n, 1)
+
+	romanized, n = romanize([]rune("국"), 0)
+	is.Equal(romanized, "guk")
+	is.Equal(n, 1)
+
+	romanized, n = romanize([]rune("シャ"), 0)
+	is.Equal(romanized, "sha")
+	is.Equal(n, 2)
+
+	romanized, n = romanize([]rune("っち"), 0)
+	is.Equal(romanized, "tchi")
+	is.Equal(n, 2)
+
+	_, n = romanize([]rune("東"), 0)
+	is.Equal(n, 0)
+}
+
+func TestEscapeASCII(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(escapeASCII("Tokyo"), "Tokyo")
+	is.Equal(escapeASCII("東京 Tower"), "u6771u4eac Tower")
+	is.Equal(escapeASCII(""), "")
+}
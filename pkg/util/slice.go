@@ -12,3 +12,19 @@ func ReverseAny(s interface{}) {
 		swap(i, j)
 	}
 }
+
+// UniqueInt64 removes duplicates of a slice of int64, keeping the first occurrences in order.
+func UniqueInt64(s []int64) []int64 {
+	result := make([]int64, 0, len(s))
+	seen := map[int64]bool{}
+	for _, v := range s {
+		if seen[v] {
+			continue
+		}
+
+		seen[v] = true
+		result = append(result, v)
+	}
+
+	return result
+}
@@ -18,3 +18,20 @@ func TestReverseAny(t *testing.T) {
 	is.Equal(ints, []int{4, 3, 2, 1})
 	is.Equal(strs, []string{"d", "c", "b", "a"})
 }
+
+func TestUniqueInt64(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		s    []int64
+		want []int64
+	}{
+		{[]int64{}, []int64{}},
+		{[]int64{49915, 1903516, 49915}, []int64{49915, 1903516}},
+		{[]int64{3, 1, 2, 1, 3}, []int64{3, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		is.Equal(UniqueInt64(tt.s), tt.want)
+	}
+}
@@ -8,7 +8,8 @@ import (
 	"golang.org/x/text/unicode/norm"
 )
 
-// NormalizeString transforms an unicode string str to ASCII form.
+// NormalizeString removes diacritics of an unicode string str.
+// See Transliterate for the ASCII form.
 func NormalizeString(str string) string {
 	normalizer := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
 	input := []byte(str)
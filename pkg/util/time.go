@@ -13,3 +13,14 @@ func ParseDuration(str string) (duration time.Duration, err error) {
 
 	return
 }
+
+// ParseDate parses a date, e.g. "2019-01-01", or a timestamp in RFC 3339, e.g. "2019-01-01T12:00:00Z".
+// Dates are at the midnight in UTC.
+func ParseDate(str string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", str)
+	if err == nil {
+		return date, nil
+	}
+
+	return time.Parse(time.RFC3339, str)
+}
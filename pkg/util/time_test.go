@@ -17,3 +17,18 @@ func TestParseDuration(t *testing.T) {
 	is.NoErr(err)
 	is.Equal(dur, time.Second*80)
 }
+
+func TestParseDate(t *testing.T) {
+	is := is.New(t)
+
+	_, err := ParseDate("2019-13-01")
+	is.True(err != nil)
+
+	date, err := ParseDate("2019-01-01")
+	is.NoErr(err)
+	is.Equal(date, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	date, err = ParseDate("2019-01-01T12:30:00+07:00")
+	is.NoErr(err)
+	is.True(date.Equal(time.Date(2019, 1, 1, 5, 30, 0, 0, time.UTC)))
+}
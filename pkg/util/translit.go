@@ -0,0 +1,177 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer forms
+const (
+	NormalizerStrip = "strip"
+	NormalizerASCII = "ascii"
+	NormalizerSlug  = "slug"
+)
+
+// Normalizer transforms an unicode string written in a language into another form.
+// The language is a BCP 47 tag which may be empty.
+type Normalizer func(str, lang string) string
+
+// ParseNormalizer returns the normalizer of a form: strip, ascii or slug.
+// An empty form means strip.
+func ParseNormalizer(form string) (Normalizer, error) {
+	switch form {
+	case "", NormalizerStrip:
+		return func(str, _ string) string {
+			return NormalizeString(str)
+		}, nil
+	case NormalizerASCII:
+		return Transliterate, nil
+	case NormalizerSlug:
+		return Slugify, nil
+	}
+
+	return nil, errors.New("invalid normalizer")
+}
+
+// Transliterate transforms an unicode string str to ASCII form following Latin-ASCII, Cyrillic, Greek and Arabic rules
+// along with the romanization of Hangul and Kana.
+// Rules of the language lang take precedence, e.g. "ü" becomes "ue" in German.
+// Han characters can't be read without a dictionary so they and other scripts without rules become their code points, e.g. "東京" becomes "u6771u4eac".
+func Transliterate(str, lang string) string {
+	overrides := translitOverrides[baseLanguage(lang)]
+	runes := []rune(norm.NFC.String(str))
+
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		// caseless scripts are capitalized at the start of words
+		start := i == 0 || !unicode.IsLetter(runes[i-1])
+		if romanized, n := romanize(runes, i); n != 0 {
+			if start && romanized != "" {
+				romanized = strings.ToUpper(romanized[:1]) + romanized[1:]
+			}
+
+			b.WriteString(romanized)
+			i += n - 1
+			continue
+		}
+
+		lower := unicode.ToLower(r)
+		replacement, ok := overrides[lower]
+		if !ok {
+			replacement, ok = translitRules[lower]
+		}
+
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+
+		if replacement == "" || (lower == r && !(start && unicode.ToUpper(r) == r)) {
+			b.WriteString(replacement)
+			continue
+		}
+
+		// capitals within an uppercase word, e.g. "ЖКХ" becomes "ZHKKH" rather than "ZhKKh"
+		if upperNeighbor(runes, i) {
+			b.WriteString(strings.ToUpper(replacement))
+			continue
+		}
+
+		b.WriteString(strings.ToUpper(replacement[:1]) + replacement[1:])
+	}
+
+	return escapeASCII(NormalizeString(b.String()))
+}
+
+// Slugify transliterates an unicode string str then transforms it to a lowercase, hyphen-separated form.
+// E.g. "Thành phố Hồ Chí Minh" becomes "thanh-pho-ho-chi-minh".
+func Slugify(str, lang string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range Transliterate(str, lang) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			hyphen = b.Len() != 0
+			continue
+		}
+
+		if hyphen {
+			b.WriteRune('-')
+			hyphen = false
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// upperNeighbor determines if the rune at i is next to another capital in the same word.
+func upperNeighbor(runes []rune, i int) bool {
+	if i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
+		return unicode.IsUpper(runes[i+1])
+	}
+
+	return i > 0 && unicode.IsUpper(runes[i-1])
+}
+
+// baseLanguage returns the primary subtag of a BCP 47 tag, e.g. "de" of "de-AT".
+func baseLanguage(lang string) string {
+	lang = strings.ToLower(lang)
+	i := strings.IndexAny(lang, "-_")
+	if i < 0 {
+		return lang
+	}
+
+	return lang[:i]
+}
+
+// translitRules maps lowercase runes to ASCII. Diacritics are removed afterwards.
+var translitRules = map[rune]string{
+	// Latin
+	'đ': "d", 'ð': "d", 'ɖ': "d", 'ß': "ss", 'ø': "o", 'æ': "ae", 'œ': "oe", 'þ': "th",
+	'ł': "l", 'ŀ': "l", 'ı': "i", 'ħ': "h", 'ŋ': "ng", 'ĸ': "q", 'ſ': "s", 'ĳ': "ij",
+	'ƒ': "f", 'ə': "e", 'ɛ': "e", 'ɔ': "o", 'ŉ': "'n",
+	// Punctuation
+	'‘': "'", '’': "'", '‚': "'", '‛': "'", '“': "\"", '”': "\"", '„': "\"", '‟': "\"",
+	'«': "<<", '»': ">>", '‹': "<", '›': ">", '‐': "-", '‑': "-", '‒': "-", '–': "-",
+	'—': "-", '―': "-", '…': "...", '·': ".", '\u00a0': " ",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u", 'ђ': "dj", 'ј': "j",
+	'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ѓ': "gj", 'ќ': "kj", 'ѕ': "dz",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o", 'ά': "a", 'έ': "e", 'ή': "i", 'ί': "i", 'ό': "o", 'ύ': "y", 'ώ': "o",
+	'ϊ': "i", 'ϋ': "y", 'ΐ': "i", 'ΰ': "y",
+	// Arabic and Persian, without the vowels which are rarely written
+	'ا': "a", 'أ': "a", 'إ': "i", 'آ': "a", 'ٱ': "a", 'ب': "b", 'ت': "t", 'ث': "th",
+	'ج': "j", 'ح': "h", 'خ': "kh", 'د': "d", 'ذ': "dh", 'ر': "r", 'ز': "z", 'س': "s",
+	'ش': "sh", 'ص': "s", 'ض': "d", 'ط': "t", 'ظ': "z", 'ع': "'", 'غ': "gh", 'ف': "f",
+	'ق': "q", 'ك': "k", 'ل': "l", 'م': "m", 'ن': "n", 'ه': "h", 'و': "w", 'ي': "y",
+	'ى': "a", 'ة': "a", 'ء': "'", 'ؤ': "'", 'ئ': "'", 'پ': "p", 'چ': "ch", 'ژ': "zh",
+	'گ': "g", 'ک': "k", 'ی': "y", '،': ",", '٠': "0", '١': "1", '٢': "2", '٣': "3",
+	'٤': "4", '٥': "5", '٦': "6", '٧': "7", '٨': "8", '٩': "9", 'ـ': "",
+}
+
+// translitOverrides maps base languages to their own rules.
+var translitOverrides = map[string]map[rune]string{
+	"de": {'ä': "ae", 'ö': "oe", 'ü': "ue"},
+	"da": {'å': "aa", 'ø': "oe"},
+	"nb": {'å': "aa", 'ø': "oe"},
+	"nn": {'å': "aa", 'ø': "oe"},
+	"no": {'å': "aa", 'ø': "oe"},
+	"uk": {'г': "h", 'и': "y", 'й': "i", 'ї': "i", 'х': "kh"},
+	"be": {'г': "h", 'х': "kh"},
+	"bg": {'щ': "sht", 'ъ': "a", 'х': "h"},
+	"sr": {'ћ': "c", 'ђ': "dj", 'х': "h"},
+	"mk": {'х': "h"},
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParseNormalizer(t *testing.T) {
+	is := is.New(t)
+
+	normalizer, err := ParseNormalizer("")
+	is.NoErr(err)
+	is.Equal(normalizer("Đà Nẵng", ""), "Đa Nang")
+
+	normalizer, err = ParseNormalizer(NormalizerASCII)
+	is.NoErr(err)
+	is.Equal(normalizer("Đà Nẵng", ""), "Da Nang")
+
+	normalizer, err = ParseNormalizer(NormalizerSlug)
+	is.NoErr(err)
+	is.Equal(normalizer("Đà Nẵng", ""), "da-nang")
+
+	_, err = ParseNormalizer("foo")
+	is.True(err != nil)
+}
+
+func TestTransliterate(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(Transliterate("Thành phố Hồ Chí Minh", "vi"), "Thanh pho Ho Chi Minh")
+	is.Equal(Transliterate("Đông Hà", "vi"), "Dong Ha")
+	is.Equal(Transliterate("Großglockner", ""), "Grossglockner")
+	is.Equal(Transliterate("Tromsø, Ærø", ""), "Tromso, Aero")
+	is.Equal(Transliterate("Łódź", "pl"), "Lodz")
+	is.Equal(Transliterate("Москва", "ru"), "Moskva")
+	is.Equal(Transliterate("Щёлково", "ru"), "Shchelkovo")
+	is.Equal(Transliterate("ЖКХ", "ru"), "ZHKKH")
+	is.Equal(Transliterate("Αθήνα", "el"), "Athina")
+	is.Equal(Transliterate("서울특별시", "ko"), "Seoulteukbyeolsi")
+	is.Equal(Transliterate("한국어", "ko"), "Hangugeo")
+	is.Equal(Transliterate("さっぽろ", "ja"), "Sapporo")
+	is.Equal(Transliterate("ホッカイドウ", "ja"), "Hokkaidou")
+	is.Equal(Transliterate("きょうと しゅっちょう", "ja"), "Kyouto Shutchou")
+	is.Equal(Transliterate("دبي", "ar"), "Dby")
+	is.Equal(Transliterate("東京", "ja"), "u6771u4eac")
+	is.Equal(Transliterate("ทะเล", "th"), "u0e17u0e30u0e40u0e25")
+
+	// overrides
+	is.Equal(Transliterate("München", ""), "Munchen")
+	is.Equal(Transliterate("München", "de"), "Muenchen")
+	is.Equal(Transliterate("Übach-Palenberg", "de-DE"), "Uebach-Palenberg")
+	is.Equal(Transliterate("Århus", "da"), "Aarhus")
+	is.Equal(Transliterate("Харків", "uk"), "Kharkiv")
+	is.Equal(Transliterate("Київ", "uk"), "Kyiv")
+	is.Equal(Transliterate("Горад", "be"), "Horad")
+}
+
+func TestSlugify(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(Slugify("Thành phố Hồ Chí Minh", "vi"), "thanh-pho-ho-chi-minh")
+	is.Equal(Slugify("  Bà Rịa – Vũng Tàu ", "vi"), "ba-ria-vung-tau")
+	is.Equal(Slugify("Quận 1", "vi"), "quan-1")
+	is.Equal(Slugify("Baden-Württemberg", "de"), "baden-wuerttemberg")
+	is.Equal(Slugify("東京都 (Tokyo)", "ja"), "u6771u4eacu90fd-tokyo")
+	is.Equal(Slugify("부산광역시", "ko"), "busangwangyeoksi")
+	is.Equal(Slugify("", ""), "")
+}